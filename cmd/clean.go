@@ -16,13 +16,8 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"os/exec"
-	"strings"
 
 	//"github.com/davecgh/go-spew/spew"
-	"github.com/dansteen/constellation/rkt"
 	"github.com/dansteen/constellation/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -57,36 +52,8 @@ func clean(cmd *cobra.Command, args []string) {
 	// get some config items
 	projectName := viper.GetString("projectName")
 	netConfigPath := viper.GetString("netConfigPath")
+	rt := GetRuntime()
 
-	// get our running containers for this project
-	allPods, err := rkt.GetAllPods(projectName)
-	util.Check(err)
-
-	// run through and clean them
-	for name, pod := range allPods.Pods {
-		log.Println(name)
-
-		// stop the container
-		command := strings.Split(fmt.Sprintf("rkt stop --force %s", pod.Name), " ")
-		log.Printf("Running: %+v", command)
-		cleanCmd := exec.Command(command[0], command[1:]...)
-		output, err := cleanCmd.CombinedOutput()
-		log.Printf("%s", output)
-		util.Check(err)
-
-		// delete the container
-		command = strings.Split(fmt.Sprintf("rkt rm %s", pod.Name), " ")
-		log.Printf("Running: %+v", command)
-		cleanCmd = exec.Command(command[0], command[1:]...)
-		output, err = cleanCmd.CombinedOutput()
-		log.Printf("%s", output)
-		util.Check(err)
-
-		log.Printf("Stopped and Removed %s", name)
-	}
-
-	// remove the network config
-	log.Println("Removing config files")
-	err = os.RemoveAll(netConfigPath)
-	util.Check(err)
+	// stop and remove our containers, and then our network and config files
+	util.Check(cleanProject(rt, projectName, netConfigPath, []string{}))
 }
@@ -0,0 +1,202 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dansteen/constellation/config"
+	"github.com/dansteen/constellation/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph [container...]",
+	Short: "Draw the dependency graph of the containers specified in the supplied config file",
+	Long: `Draw the order containers are started in as a Graphviz DOT graph, a Mermaid flowchart or an ASCII tree.  Each
+container is shown with its image, the types of its state conditions and whether it is transient (has an exit condition
+with a status of success).  An arrow from one container to another means the second depends on the first.  Containers
+are picked the same way run picks them: if containers are named, only they and the containers they depend on are drawn.
+No container runtime is needed.`,
+	Run: graph,
+}
+
+func init() {
+	RootCmd.AddCommand(graphCmd)
+
+	graphCmd.Flags().String("format", "ascii", "The format to draw the graph in.  One of ascii, dot or mermaid")
+	graphCmd.Flags().StringSlice("profile", make([]string, 0), "Also draw the containers with this profile.  Can be given more than once")
+
+	viper.BindPFlag("graph-format", graphCmd.Flags().Lookup("format"))
+	viper.BindPFlag("graph-profile", graphCmd.Flags().Lookup("profile"))
+}
+
+// graphNode holds what we show of a single container
+type graphNode struct {
+	name       string
+	image      string
+	conditions []string
+	transient  bool
+	dependsOn  []string
+}
+
+// labels returns the lines describing this node
+func (node graphNode) labels() []string {
+	labels := []string{node.name, "image: " + node.image}
+	if len(node.conditions) > 0 {
+		labels = append(labels, "conditions: "+strings.Join(node.conditions, ", "))
+	}
+	if node.transient {
+		labels = append(labels, "transient")
+	}
+	return labels
+}
+
+func graph(cmd *cobra.Command, args []string) {
+	BaseInit()
+	constellationFile, overrideFiles := GetConfigFiles()
+	includeDirs := viper.GetStringSlice("includeDirs")
+	format := viper.GetString("graph-format")
+	profiles := viper.GetStringSlice("graph-profile")
+
+	configData := config.ProcessFile(constellationFile, overrideFiles, includeDirs, GetVariables())
+	selectContainers(&configData, profiles, args)
+	order, err := configData.DependencyOrder()
+	util.Check(err)
+
+	nodes := make([]graphNode, 0)
+	for _, name := range order {
+		ourContainer := configData.Containers[name]
+		nodes = append(nodes, graphNode{
+			name:       name,
+			image:      ourContainer.Image,
+			conditions: ourContainer.StateConditions.Types(),
+			transient:  ourContainer.Transient(),
+			dependsOn:  ourContainer.DependsStrings,
+		})
+	}
+
+	switch format {
+	case "ascii":
+		printASCIIGraph(nodes)
+	case "dot":
+		printDOTGraph(nodes)
+	case "mermaid":
+		printMermaidGraph(nodes)
+	default:
+		util.Check(errors.New(fmt.Sprintf("--format must be one of ascii, dot or mermaid.  Got %s", format)))
+	}
+}
+
+// printDOTGraph prints our nodes as a Graphviz digraph.  Transient containers are drawn dashed
+func printDOTGraph(nodes []graphNode) {
+	quote := func(value string) string {
+		return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+	}
+	fmt.Println("digraph constellation {")
+	fmt.Println("  rankdir=LR;")
+	fmt.Println("  node [shape=box];")
+	for _, node := range nodes {
+		labels := make([]string, 0)
+		for _, label := range node.labels() {
+			labels = append(labels, strings.Replace(label, `"`, `\"`, -1))
+		}
+		style := ""
+		if node.transient {
+			style = ", style=dashed"
+		}
+		fmt.Printf("  %s [label=\"%s\"%s];\n", quote(node.name), strings.Join(labels, `\n`), style)
+	}
+	for _, node := range nodes {
+		for _, dep := range node.dependsOn {
+			fmt.Printf("  %s -> %s;\n", quote(dep), quote(node.name))
+		}
+	}
+	fmt.Println("}")
+}
+
+// printMermaidGraph prints our nodes as a Mermaid flowchart.  Transient containers are drawn with rounded ends
+func printMermaidGraph(nodes []graphNode) {
+	// container names can have characters mermaid does not allow in ids, so we number them instead
+	ids := make(map[string]string)
+	for index, node := range nodes {
+		ids[node.name] = fmt.Sprintf("n%d", index)
+	}
+	fmt.Println("flowchart LR")
+	for _, node := range nodes {
+		labels := make([]string, 0)
+		for _, label := range node.labels() {
+			labels = append(labels, strings.Replace(label, `"`, "#quot;", -1))
+		}
+		start, end := "[", "]"
+		if node.transient {
+			start, end = "([", "])"
+		}
+		fmt.Printf("  %s%s\"%s\"%s\n", ids[node.name], start, strings.Join(labels, "<br/>"), end)
+	}
+	for _, node := range nodes {
+		for _, dep := range node.dependsOn {
+			fmt.Printf("  %s --> %s\n", ids[dep], ids[node.name])
+		}
+	}
+}
+
+// printASCIIGraph prints our nodes as a tree, starting from the containers that do not depend on anything.  Containers
+// that depend on more than one container are only expanded the first time they are shown.
+func printASCIIGraph(nodes []graphNode) {
+	// work out what depends on each container
+	dependents := make(map[string][]graphNode)
+	for _, node := range nodes {
+		for _, dep := range node.dependsOn {
+			dependents[dep] = append(dependents[dep], node)
+		}
+	}
+
+	shown := make(map[string]bool)
+	var printNode func(node graphNode, prefix string, branch string, indent string)
+	printNode = func(node graphNode, prefix string, branch string, indent string) {
+		if shown[node.name] {
+			fmt.Printf("%s%s%s (see above)\n", prefix, branch, node.name)
+			return
+		}
+		shown[node.name] = true
+		description := fmt.Sprintf("%s [%s]", node.name, node.image)
+		if len(node.conditions) > 0 {
+			description += " " + strings.Join(node.conditions, ", ")
+		}
+		if node.transient {
+			description += " (transient)"
+		}
+		fmt.Printf("%s%s%s\n", prefix, branch, description)
+
+		children := dependents[node.name]
+		for index, child := range children {
+			if index == len(children)-1 {
+				printNode(child, prefix+indent, "`-- ", "    ")
+			} else {
+				printNode(child, prefix+indent, "|-- ", "|   ")
+			}
+		}
+	}
+	for _, node := range nodes {
+		if len(node.dependsOn) == 0 {
+			printNode(node, "", "", "")
+		}
+	}
+}
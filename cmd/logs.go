@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dansteen/constellation/config"
+	"github.com/dansteen/constellation/container"
+	"github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/types"
+	"github.com/dansteen/constellation/util"
+	"github.com/fatih/color"
+	"github.com/hpcloud/tail"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs [container...]",
+	Short: "Print the output of containers in a project",
+	Long: `Print the output of the named containers in the project defined with -p.  If no containers are named, the output
+of every container in the supplied config file is printed.  Output is read from the log file of a container if it has one
+(see --log-dir and log_file), and from the runtime otherwise.`,
+	Run: logs,
+}
+
+func init() {
+	RootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep streaming new output")
+	logsCmd.Flags().String("since", "", "Only show output since this time.  Either a duration (e.g. 10m) or an RFC3339 timestamp")
+	logsCmd.Flags().Int("tail", -1, "Only show this many of the most recent lines of each container.  -1 shows all lines")
+
+	viper.BindPFlag("follow", logsCmd.Flags().Lookup("follow"))
+	viper.BindPFlag("since", logsCmd.Flags().Lookup("since"))
+	viper.BindPFlag("tail", logsCmd.Flags().Lookup("tail"))
+}
+
+func logs(cmd *cobra.Command, args []string) {
+	BaseInit()
+	// get some config items
+	projectName := viper.GetString("projectName")
+	netConfigPath := viper.GetString("netConfigPath")
+	constellationFile, overrideFiles := GetConfigFiles()
+	includeDirs := viper.GetStringSlice("includeDirs")
+	logDir := viper.GetString("log-dir")
+	options := types.LogOptions{
+		Follow: viper.GetBool("follow"),
+		Tail:   viper.GetInt("tail"),
+	}
+	if since := viper.GetString("since"); since != "" {
+		sinceTime, err := parseSince(since)
+		util.Check(err)
+		options.Since = sinceTime
+	}
+	rt := GetRuntime()
+
+	// load our config if we have one so we can find the log files of our containers
+	configData := config.Config{}
+	if constellationFile != "" {
+		configData = config.ProcessFile(constellationFile, overrideFiles, includeDirs, GetVariables())
+	}
+
+	// work out which containers we want the output of
+	names := args
+	if len(names) == 0 {
+		order, err := configData.DependencyOrder()
+		util.Check(err)
+		names = order
+	}
+	if len(names) == 0 {
+		util.Check(errors.New("No containers to print the output of.  Name some containers or supply a config file with -c"))
+	}
+
+	// get all of our pods
+	allPods, err := rt.GetAllPods(projectName)
+	util.Check(err)
+
+	// and work out where to get the output of each of them
+	printers := make(map[string]logsPrinter)
+	for _, name := range names {
+		// containers that saved their output to a log file are read from there
+		ourContainer, ok := configData.Containers[name]
+		if !ok {
+			ourContainer = &container.Container{Name: name}
+		}
+		ourContainer.SetLogFile(logDir, netConfigPath)
+		if _, err := os.Stat(ourContainer.LogFile); ourContainer.LogFile != "" && err == nil {
+			printers[name] = newLogsFile(name, ourContainer.LogFile, options)
+			continue
+		}
+
+		// otherwise we ask the runtime
+		appName, err := rt.GetAppName(projectName, name)
+		util.Check(err)
+		pod, ok := allPods.Pods[appName]
+		if !ok {
+			util.Check(errors.New(fmt.Sprintf("There is no pod or log file for %s in project %s", name, projectName)))
+		}
+		command, err := newLogsCommand(rt, name, appName, pod, options)
+		util.Check(err)
+		printers[name] = command
+	}
+
+	// when following we stream everything at once, otherwise we print each container in turn so the output stays together
+	if options.Follow {
+		var wait sync.WaitGroup
+		for _, name := range names {
+			wait.Add(1)
+			go func(printer logsPrinter) {
+				defer wait.Done()
+				util.Check(printer.print())
+			}(printers[name])
+		}
+		wait.Wait()
+	} else {
+		for _, name := range names {
+			util.Check(printers[name].print())
+		}
+	}
+}
+
+// logsPrinter prints the output of a single container prefixed with the container name
+type logsPrinter interface {
+	print() error
+}
+
+// logPrefix generates the colored prefix for the output of a container
+func logPrefix(name string) string {
+	ourColor := color.New(util.RandomColor()...).SprintfFunc()
+	return fmt.Sprintf("[%s] ", ourColor(name))
+}
+
+// logsCommand prints the output of a container that is retrieved from the runtime
+type logsCommand struct {
+	name    string
+	prefix  string
+	command *exec.Cmd
+}
+
+// newLogsCommand will set up the command that retrieves the output of a container from the runtime
+func newLogsCommand(rt runtime.Runtime, name string, appName string, pod types.Pod, options types.LogOptions) (*logsCommand, error) {
+	command, err := rt.LogsCommand(pod, appName, options)
+	if err != nil {
+		return nil, err
+	}
+	return &logsCommand{
+		name:    name,
+		prefix:  logPrefix(name),
+		command: command,
+	}, nil
+}
+
+// print will run the command and print its output
+func (logs *logsCommand) print() error {
+	// we combine stdout and stderr since some runtimes replay container output on both
+	reader, writer := io.Pipe()
+	logs.command.Stdout = writer
+	logs.command.Stderr = writer
+	err := logs.command.Start()
+	if err != nil {
+		return err
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- logs.command.Wait()
+		writer.Close()
+	}()
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fmt.Fprintf(os.Stdout, "%s%s\n", logs.prefix, scanner.Text())
+	}
+	err = <-result
+	if err != nil {
+		log.Printf("Could not get the output of %s: %s", logs.name, err)
+	}
+	return err
+}
+
+// logsFile prints the output of a container from the log file it was saved to
+type logsFile struct {
+	name    string
+	prefix  string
+	path    string
+	options types.LogOptions
+}
+
+// newLogsFile will set up printing the output of a container from its log file
+func newLogsFile(name string, path string, options types.LogOptions) *logsFile {
+	return &logsFile{
+		name:    name,
+		prefix:  logPrefix(name),
+		path:    path,
+		options: options,
+	}
+}
+
+// print will print the lines of the log file (and any rotated log files) that match our options
+func (logs *logsFile) print() error {
+	lines := make([]string, 0)
+	// offset is how far into the current log file we have read, which is where following it picks up
+	offset := int64(0)
+	for _, filePath := range append(util.RotatedPaths(logs.path, container.LogFileMaxFiles), logs.path) {
+		current := filePath == logs.path
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		reader := bufio.NewReader(file)
+		for {
+			line, err := reader.ReadString('\n')
+			if err == io.EOF {
+				// a line that is still being written is left for following to pick up
+				if line != "" && !(current && logs.options.Follow) && logs.include(line) {
+					lines = append(lines, line)
+				}
+				break
+			}
+			if err != nil {
+				file.Close()
+				return err
+			}
+			if current {
+				offset += int64(len(line))
+			}
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if logs.include(line) {
+				lines = append(lines, line)
+			}
+		}
+		file.Close()
+	}
+	if logs.options.Tail >= 0 && logs.options.Tail < len(lines) {
+		lines = lines[len(lines)-logs.options.Tail:]
+	}
+	for _, line := range lines {
+		fmt.Fprintf(os.Stdout, "%s%s\n", logs.prefix, line)
+	}
+	if !logs.options.Follow {
+		return nil
+	}
+
+	// then we follow the file from where we stopped reading it, so that nothing written in between is lost
+	follow, err := tail.TailFile(logs.path, tail.Config{
+		Follow:    true,
+		ReOpen:    true,
+		MustExist: true,
+		Location: &tail.SeekInfo{
+			Offset: offset,
+			Whence: 0,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for line := range follow.Lines {
+		fmt.Fprintf(os.Stdout, "%s%s\n", logs.prefix, line.Text)
+	}
+	return follow.Err()
+}
+
+// include checks if a line from a log file was logged within the time covered by our options
+func (logs *logsFile) include(line string) bool {
+	if logs.options.Since.IsZero() {
+		return true
+	}
+	logged, _, _, err := container.ParseLogLine(line)
+	return err != nil || !logged.Before(logs.options.Since)
+}
+
+// parseSince will parse the value of --since, which is either a duration before now or a timestamp
+func parseSince(since string) (time.Time, error) {
+	if duration, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	sinceTime, err := time.Parse(time.RFC3339, strings.TrimSpace(since))
+	if err != nil {
+		return sinceTime, errors.New(fmt.Sprintf("--since must be a duration (e.g. 10m) or an RFC3339 timestamp.  Got %s", since))
+	}
+	return sinceTime, nil
+}
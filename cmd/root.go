@@ -15,10 +15,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/dansteen/constellation/config"
+	"github.com/dansteen/constellation/docker"
+	"github.com/dansteen/constellation/fake"
+	"github.com/dansteen/constellation/rkt"
+	"github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/util"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -52,13 +59,18 @@ func init() {
 	// Cobra supports Persistent Flags, which, if defined here,
 	// will be global for your application.
 
-	RootCmd.PersistentFlags().StringP("constellationFile", "c", "", "path to a yaml file defining your constellation")
+	RootCmd.PersistentFlags().StringSliceP("constellationFile", "c", make([]string, 0), "path to a yaml file defining your constellation.  Repeat to apply override files on top of it")
 	RootCmd.PersistentFlags().StringP("projectName", "p", "", "an arbitrary name to identify this invocation")
 	RootCmd.PersistentFlags().StringSliceP("includeDirs", "I", make([]string, 0), "Directories to look in for files included via the 'require' stanza")
 	RootCmd.PersistentFlags().StringSliceP("volumeOverrides", "v", make([]string, 0), "Set this if you want to override the volume locations set in the constellation file.")
 	RootCmd.PersistentFlags().StringSliceP("imageOverrides", "i", make([]string, 0), "Set this if you want to override the image versions set in the constellation file")
 	RootCmd.PersistentFlags().StringSliceP("hostsEntries", "H", make([]string, 0), "Use this to add any local resources into all of the containers generated by constellation")
 	RootCmd.PersistentFlags().Bool("no-color", false, "Disable color output")
+	RootCmd.PersistentFlags().String("log-dir", "", "Save the output of every container to <log-dir>/<container>.log")
+	RootCmd.PersistentFlags().String("env-file", "", "A file of NAME=value lines to substitute into ${NAME} references in the constellation file")
+	RootCmd.PersistentFlags().String("runtime", "rkt", "The container runtime to use.  One of rkt, docker or podman")
+	RootCmd.PersistentFlags().String("fake-fixture", "", "The fixture file that drives the fake runtime")
+	RootCmd.PersistentFlags().MarkHidden("fake-fixture")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -72,6 +84,10 @@ func init() {
 	viper.BindPFlag("hostsEntries", RootCmd.PersistentFlags().Lookup("hostsEntries"))
 	viper.BindPFlag("imageOverrides", RootCmd.PersistentFlags().Lookup("imageOverrides"))
 	viper.BindPFlag("no-color", RootCmd.PersistentFlags().Lookup("no-color"))
+	viper.BindPFlag("log-dir", RootCmd.PersistentFlags().Lookup("log-dir"))
+	viper.BindPFlag("env-file", RootCmd.PersistentFlags().Lookup("env-file"))
+	viper.BindPFlag("runtime", RootCmd.PersistentFlags().Lookup("runtime"))
+	viper.BindPFlag("fake-fixture", RootCmd.PersistentFlags().Lookup("fake-fixture"))
 	viper.BindPFlag("debug", RootCmd.PersistentFlags().Lookup("debug"))
 }
 
@@ -85,9 +101,47 @@ func BaseInit() {
 	}
 
 	// we need to do some post-processing here due to this: https://github.com/spf13/viper/issues/200
-	for _, entry := range []string{"includeDirs", "volumeOverrides", "imageOverrides", "hostsEntries"} {
+	for _, entry := range []string{"constellationFile", "includeDirs", "volumeOverrides", "imageOverrides", "hostsEntries", "profile", "graph-profile"} {
 		if viper.IsSet(entry) && len(viper.GetString(entry)) != 0 {
 			viper.Set(entry, strings.Split(viper.GetString(entry), ","))
 		}
 	}
 }
+
+// GetRuntime will return the container runtime to use for this invocation
+func GetRuntime() runtime.Runtime {
+	name := viper.GetString("runtime")
+	switch name {
+	case "rkt":
+		return &rkt.Runtime{}
+	case "docker", "podman":
+		return &docker.Runtime{Binary: name}
+	case "fake":
+		fixture, err := fake.LoadFixture(viper.GetString("fake-fixture"))
+		util.Check(err)
+		return fake.New(fixture, viper.GetString("netConfigPath"))
+	case "":
+		util.Check(errors.New("No runtime specified"))
+	}
+	util.Check(errors.New(fmt.Sprintf("Unknown runtime: %s", name)))
+	return nil
+}
+
+// GetConfigFiles will return the constellation file to use, and the override files to apply on top of it
+func GetConfigFiles() (string, []string) {
+	files := viper.GetStringSlice("constellationFile")
+	if len(files) == 0 {
+		return "", make([]string, 0)
+	}
+	return files[0], files[1:]
+}
+
+// GetVariables will return the variables to substitute into config files, from the env file if one was provided
+func GetVariables() map[string]string {
+	if viper.GetString("env-file") == "" {
+		return make(map[string]string)
+	}
+	variables, err := config.LoadEnvFile(viper.GetString("env-file"))
+	util.Check(err)
+	return variables
+}
@@ -15,16 +15,19 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"sync"
+	"syscall"
 	"text/tabwriter"
 
 	"github.com/dansteen/constellation/config"
+	"github.com/dansteen/constellation/container"
 	"github.com/dansteen/constellation/types"
 	"github.com/dansteen/constellation/util"
 	//"github.com/davecgh/go-spew/spew"
@@ -34,10 +37,11 @@ import (
 
 // runCmd represents the run command
 var runCmd = &cobra.Command{
-	Use:   "run",
+	Use:   "run [container...]",
 	Short: "Run the containers specified in the supplied config file",
-	Long:  ``,
-	Run:   run,
+	Long: `Run the containers specified in the supplied config file.  If containers are named, only they and the containers
+they depend on are run.`,
+	Run: run,
 }
 
 func init() {
@@ -52,7 +56,15 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// runCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	runCmd.Flags().Int("max-parallel", 0, "The maximum number of containers to start at the same time.  0 means no limit")
+	runCmd.Flags().String("on-failure", "leave", "What to do with the containers already started when a container fails.  One of leave, stop or clean")
+	runCmd.Flags().Bool("foreground", false, "Stay attached after the containers have started, and stop them on SIGINT or SIGTERM")
+	runCmd.Flags().StringSlice("profile", make([]string, 0), "Also run the containers with this profile.  Can be given more than once")
 
+	viper.BindPFlag("max-parallel", runCmd.Flags().Lookup("max-parallel"))
+	viper.BindPFlag("on-failure", runCmd.Flags().Lookup("on-failure"))
+	viper.BindPFlag("foreground", runCmd.Flags().Lookup("foreground"))
+	viper.BindPFlag("profile", runCmd.Flags().Lookup("profile"))
 }
 
 func run(cmd *cobra.Command, args []string) {
@@ -61,30 +73,29 @@ func run(cmd *cobra.Command, args []string) {
 	// grab some config items
 	projectName := viper.GetString("projectName")
 	netConfigPath := viper.GetString("netConfigPath")
-	constellationFile := viper.GetString("constellationFile")
+	constellationFile, overrideFiles := GetConfigFiles()
 	includeDirs := viper.GetStringSlice("includeDirs")
 	imageOverrides := viper.GetStringSlice("imageOverrides")
 	volumeOverrides := viper.GetStringSlice("volumeOverrides")
 	hostsEntries := viper.GetStringSlice("hostsEntries")
+	maxParallel := viper.GetInt("max-parallel")
+	onFailure := viper.GetString("on-failure")
+	foreground := viper.GetBool("foreground")
+	logDir := viper.GetString("log-dir")
+	profiles := viper.GetStringSlice("profile")
 
-	// generate our network file
-	netConfigFile := fmt.Sprintf("%s/net.d/%s.conf", netConfigPath, projectName)
-	// first check if we already have a config file for this project
-	if _, err := os.Stat(netConfigFile); os.IsNotExist(err) {
-		network, err := types.NewNetworkConfig(projectName)
-		util.Check(err)
-		// convert our config to json
-		networkJSON, err := json.MarshalIndent(network, "", "  ")
-		// make our config folder
-		util.Check(os.MkdirAll(fmt.Sprintf("%s/net.d", netConfigPath), 0755))
-		// write our network files
-		util.Check(ioutil.WriteFile(netConfigFile, networkJSON, 0644))
-	} else {
-		log.Printf("Using config from previous project run: %s", netConfigPath)
+	// make sure we know what to do on failure before we start anything
+	if !contains(onFailurePolicies, onFailure) {
+		util.Check(errors.New(fmt.Sprintf("--on-failure must be one of %s.  Got %s", strings.Join(onFailurePolicies, ", "), onFailure)))
 	}
+	// process our configs.  we do this before touching the runtime so that problems with them are found first
+	configData := config.ProcessFile(constellationFile, overrideFiles, includeDirs, GetVariables())
+	selectContainers(&configData, profiles, args)
+
+	rt := GetRuntime()
 
-	// process our configs
-	configData := config.ProcessFile(constellationFile, includeDirs)
+	// set up the network for our project
+	util.Check(rt.CreateNetwork(projectName, netConfigPath))
 
 	// handle image overrides passed into the command line.
 	imageRE := regexp.MustCompile("(:^|[^/]*/)?([^:]*):?(.*)")
@@ -137,9 +148,10 @@ func run(cmd *cobra.Command, args []string) {
 		customHosts = append(customHosts, host)
 	}
 
-	// initialize the containers
+	// initialize the containers.  their log files are opened here, so we need to know where they are first
 	for _, container := range configData.Containers {
-		util.Check(container.Init(configData.Containers, configData.Volumes))
+		container.SetLogFile(logDir, netConfigPath)
+		util.Check(container.Init(rt, configData.Containers, configData.Volumes))
 	}
 
 	// make sure to create our log volumes
@@ -153,17 +165,52 @@ func run(cmd *cobra.Command, args []string) {
 	//spew.Dump(configData)
 
 	// print out the execution order
-	log.Println("Will start containers in the following order:")
+	log.Println("Will start containers in the following order (containers that do not depend on each other are started in parallel):")
 	for _, name := range order {
 		log.Printf("\t%s\n", name)
 	}
 
-	for _, containerName := range order {
-		// grab our container
-		container := configData.Containers[containerName]
-		// run our container
-		err := container.Run(netConfigPath, projectName, configData.Volumes, customHosts)
-		util.Check(err)
+	// stopping is closed once we start shutting down so that liveness checks do not report the containers we stop
+	stopping := make(chan bool)
+	var stopOnce sync.Once
+	shutdown := func() {
+		stopOnce.Do(func() { close(stopping) })
+	}
+	// a signal and a failure can arrive together, so whichever comes first cleans up and picks our exit code.  Anything
+	// that comes after it waits here until we exit.
+	var exitOnce sync.Once
+	exit := func(code int, cleanup func() error) {
+		exitOnce.Do(func() {
+			shutdown()
+			util.Check(cleanup())
+			closeLogFiles(configData.Containers)
+			os.Exit(code)
+		})
+	}
+	fail := func() error {
+		return handleFailure(rt, onFailure, projectName, netConfigPath, order)
+	}
+
+	// in foreground mode we stop our containers when we are told to stop, even if we are still starting them up
+	if foreground {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			received := <-signals
+			exit(0, func() error {
+				log.Printf("Received %s.  Stopping the containers of project %s", received, projectName)
+				return stopProject(rt, projectName, order, false)
+			})
+		}()
+	}
+
+	// run our containers
+	err = startContainers(configData.Containers, order, maxParallel, func(container *container.Container) error {
+		return container.Run(rt, netConfigPath, projectName, configData.Volumes, customHosts)
+	})
+	if err != nil {
+		log.Println(err)
+		exit(1, fail)
 	}
 
 	// grab our host address
@@ -172,7 +219,7 @@ func run(cmd *cobra.Command, args []string) {
 	output := tabwriter.NewWriter(os.Stdout, 0, 4, 0, ' ', 0)
 	for name, container := range configData.Containers {
 		// we only print out infomration for containers that are not expected to exit
-		if container.StateConditions.Exit == nil || container.StateConditions.Exit.Status != "success" {
+		if !container.Transient() {
 			for _, port := range container.Ports {
 				// TODO: get the address of the default gw interafce and print it here
 				fmt.Fprintf(output, "%s/%s -->\t %s:%d\n", name, port.Name, address, port.HostPort)
@@ -180,4 +227,80 @@ func run(cmd *cobra.Command, args []string) {
 		}
 	}
 	output.Flush()
+
+	// unless we are staying attached, we are done saving the output of our containers
+	if !foreground {
+		closeLogFiles(configData.Containers)
+	}
+
+	// in foreground mode we keep streaming the output of our containers until we receive a signal
+	if foreground {
+		log.Println("Running in the foreground.  Press Ctrl-C to stop.")
+		// keep an eye on anything with liveness conditions or a restart policy
+		for _, name := range order {
+			ourContainer := configData.Containers[name]
+			if ourContainer.Transient() {
+				continue
+			}
+			go func(ourContainer *container.Container) {
+				for {
+					err := ourContainer.Monitor(stopping)
+					if err == nil {
+						return
+					}
+					log.Printf("%s is no longer live: %s", ourContainer.Name, err)
+
+					// restart it if we are allowed to (and are not shutting down)
+					select {
+					case <-stopping:
+						return
+					default:
+					}
+					restarted, err := ourContainer.Recover(rt, netConfigPath, projectName, configData.Volumes, customHosts, err)
+					if restarted && err == nil {
+						continue
+					}
+					if restarted {
+						log.Printf("%s could not be restarted: %s", ourContainer.Name, err)
+					}
+
+					if ourContainer.Liveness == nil {
+						return
+					}
+					switch ourContainer.Liveness.Action {
+					case "stop":
+						util.Check(stopContainer(rt, projectName, ourContainer.Name))
+					case "fail":
+						exit(1, fail)
+					}
+					return
+				}
+			}(ourContainer)
+		}
+		select {}
+	}
+}
+
+// closeLogFiles will close the log files of all of our containers
+func closeLogFiles(containers map[string]*container.Container) {
+	for _, ourContainer := range containers {
+		err := ourContainer.CloseLogFile()
+		if err != nil {
+			log.Printf("Could not close the log file of %s: %s", ourContainer.Name, err)
+		}
+	}
+}
+
+// selectContainers will leave out the containers of configData whose profiles are not enabled, and if names are given,
+// the containers that none of them depend on.  Named containers are kept whatever their profiles are.
+func selectContainers(configData *config.Config, profiles []string, names []string) {
+	for _, name := range names {
+		if ourContainer, ok := configData.Containers[name]; ok {
+			profiles = append(profiles, ourContainer.Profiles...)
+		}
+	}
+	util.Check(configData.EnableProfiles(profiles))
+	if len(names) > 0 {
+		util.Check(configData.Only(names))
+	}
 }
@@ -0,0 +1,734 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// testArgsVariable holds the arguments to run constellation with when the test binary is re-run by testProject.constellation.
+// Failures end in os.Exit, so each invocation gets a process of its own.
+const testArgsVariable = "CONSTELLATION_TEST_ARGS"
+
+func TestMain(m *testing.M) {
+	if args := os.Getenv(testArgsVariable); args != "" {
+		RootCmd.SetArgs(strings.Split(args, "\n"))
+		Execute()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testProject is a constellation file and fake runtime fixture in a directory of their own
+type testProject struct {
+	t    *testing.T
+	dir  string
+	name string
+}
+
+// projectCount keeps the names of the projects in a test run apart
+var projectCount = 0
+
+// newTestProject writes config and fixture into a new directory.  $DIR in either is replaced with that directory.  The
+// project is cleaned up once the test is done.
+func newTestProject(t *testing.T, config string, fixture string) *testProject {
+	dir, err := ioutil.TempDir("", "constellation-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectCount++
+	project := &testProject{t: t, dir: dir, name: fmt.Sprintf("test%d-%d", os.Getpid(), projectCount)}
+	project.write("constellation.yml", config)
+	project.write("fixture.yml", fixture)
+	t.Cleanup(func() {
+		project.constellation("clean")
+		os.RemoveAll(dir)
+	})
+	return project
+}
+
+// write creates a file in the project directory
+func (project *testProject) write(name string, contents string) {
+	contents = strings.Replace(contents, "$DIR", project.dir, -1)
+	err := ioutil.WriteFile(filepath.Join(project.dir, name), []byte(contents), 0644)
+	if err != nil {
+		project.t.Fatal(err)
+	}
+}
+
+// constellation runs a constellation command against the project and returns its output and exit code
+func (project *testProject) constellation(command string, args ...string) (string, int) {
+	args = append([]string{command, "-c", "constellation.yml", "-p", project.name, "--runtime=fake",
+		"--fake-fixture=fixture.yml", "--no-color"}, args...)
+	run := exec.Command(os.Args[0], "-test.run=^$")
+	run.Dir = project.dir
+	run.Env = append(os.Environ(), fmt.Sprintf("%s=%s", testArgsVariable, strings.Join(args, "\n")))
+	output, err := run.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return string(output), exitErr.ExitCode()
+	} else if err != nil {
+		project.t.Fatal(err)
+	}
+	return string(output), 0
+}
+
+// run runs the project and fails the test if the exit code is not expected
+func (project *testProject) run(expected int, args ...string) string {
+	output, code := project.constellation("run", args...)
+	if code != expected {
+		project.t.Fatalf("run exited with %d rather than %d:\n%s", code, expected, output)
+	}
+	return output
+}
+
+// assertContains fails the test if output does not match each of patterns
+func assertContains(t *testing.T, output string, patterns ...string) {
+	t.Helper()
+	for _, pattern := range patterns {
+		if !regexp.MustCompile(pattern).MatchString(output) {
+			t.Errorf("output does not match %q:\n%s", pattern, output)
+		}
+	}
+}
+
+// assertBefore fails the test if first does not appear in output before second
+func assertBefore(t *testing.T, output string, first string, second string) {
+	t.Helper()
+	firstIndex := strings.Index(output, first)
+	secondIndex := strings.Index(output, second)
+	if firstIndex < 0 || secondIndex < 0 || firstIndex > secondIndex {
+		t.Errorf("expected %q before %q:\n%s", first, second, output)
+	}
+}
+
+const orderConfig = `
+containers:
+  db.local:
+    image: db:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready to accept
+          status: success
+      timeout:
+        duration: 5
+        status: failure
+  api.migrate.tmp:
+    image: api:1
+    state_conditions:
+      exit:
+        codes: [0]
+        status: success
+    depends_on:
+      - db.local
+  api.app.local:
+    image: api:1
+    state_conditions:
+      output:
+        - source: STDERR
+          regex: listening
+          status: success
+    depends_on:
+      - api.migrate.tmp
+`
+
+const orderFixture = `
+images:
+  api:1:
+    app:
+      ports:
+        - name: http
+          protocol: tcp
+          port: 8080
+pods:
+  db.local:
+    steps:
+      - stdout: booting
+      - delay: 0.5
+      - stdout: ready to accept connections
+  api.migrate.tmp:
+    steps:
+      - stdout: migrating
+      - delay: 0.2
+    exit_code: 0
+  api.app.local:
+    steps:
+      - stderr: listening on 8080
+`
+
+func TestRunStartOrder(t *testing.T) {
+	project := newTestProject(t, orderConfig, orderFixture)
+	output := project.run(0)
+
+	assertBefore(t, output, "STDOUT matched ready to accept", "echo 'migrating'")
+	assertBefore(t, output, "Received Exit Code: 0", "echo 'listening on 8080'")
+	assertContains(t, output, `STDERR matched listening`)
+}
+
+func TestRunPortTable(t *testing.T) {
+	project := newTestProject(t, orderConfig, orderFixture)
+	output := project.run(0)
+
+	assertContains(t, output, `(?m)^api\.app\.local/http -->\s+\S+:\d+$`)
+	// transient containers are left out
+	if strings.Contains(output, "api.migrate.tmp/http") {
+		t.Errorf("port table lists a transient container:\n%s", output)
+	}
+}
+
+func TestRunConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		fixture  string
+		exitCode int
+		patterns []string
+	}{
+		{
+			name: "output success",
+			config: `
+containers:
+  app.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready
+          status: success`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - stdout: ready`,
+			patterns: []string{`STDOUT matched ready`},
+		},
+		{
+			name: "output failure",
+			config: `
+containers:
+  app.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDERR
+          regex: panic
+          status: failure`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - stderr: panic at startup`,
+			exitCode: 1,
+			patterns: []string{`app\.local failed`, `panic`},
+		},
+		{
+			name: "exit success",
+			config: `
+containers:
+  app.tmp:
+    image: app:1
+    state_conditions:
+      exit:
+        codes: [0, 2]
+        status: success`,
+			fixture: `
+pods:
+  app.tmp:
+    exit_code: 2`,
+			patterns: []string{`Received Exit Code: 2`},
+		},
+		{
+			name: "exit failure",
+			config: `
+containers:
+  app.tmp:
+    image: app:1
+    state_conditions:
+      exit:
+        codes: [3]
+        status: failure`,
+			fixture: `
+pods:
+  app.tmp:
+    exit_code: 3`,
+			exitCode: 1,
+			patterns: []string{`app\.tmp failed`},
+		},
+		{
+			name: "timeout success",
+			config: `
+containers:
+  app.local:
+    image: app:1
+    state_conditions:
+      timeout:
+        duration: 1
+        status: success`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - stdout: starting`,
+			patterns: []string{`Timeout`},
+		},
+		{
+			name: "timeout failure",
+			config: `
+containers:
+  app.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready
+          status: success
+      timeout:
+        duration: 1
+        status: failure`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - stdout: starting`,
+			exitCode: 1,
+			patterns: []string{`app\.local failed: Hit Timeout`},
+		},
+		{
+			name: "filemonitor success",
+			config: `
+volumes:
+  logs:
+    kind: host
+    path: $DIR/logs
+    mode: 0755
+containers:
+  app.local:
+    image: app:1
+    mounts:
+      - volume: logs
+        path: /var/log/app
+    state_conditions:
+      filemonitor:
+        - file: /var/log/app/app.log
+          regex: Started
+          status: success
+      timeout:
+        duration: 5
+        status: failure`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - delay: 0.5
+      - write:
+          file: $DIR/logs/app.log
+          line: Started`,
+			patterns: []string{`Matched \S+/logs/app\.log to Started\. Success`},
+		},
+		{
+			name: "filemonitor failure",
+			config: `
+volumes:
+  logs:
+    kind: host
+    path: $DIR/logs
+    mode: 0755
+containers:
+  app.local:
+    image: app:1
+    mounts:
+      - volume: logs
+        path: /var/log/app
+    state_conditions:
+      filemonitor:
+        - file: /var/log/app/app.log
+          regex: Fatal
+          status: failure
+      timeout:
+        duration: 5
+        status: success`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - delay: 0.5
+      - write:
+          file: $DIR/logs/app.log
+          line: Fatal error`,
+			exitCode: 1,
+			patterns: []string{`app\.local failed: Matched \S+/logs/app\.log to Fatal\. Specified as failure`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			project := newTestProject(t, test.config, "images:\n  app:1:\n    app: {}\n"+test.fixture)
+			output := project.run(test.exitCode)
+			assertContains(t, output, test.patterns...)
+			// failures are only reported once
+			if test.exitCode != 0 && strings.Count(output, " failed: ") != 1 {
+				t.Errorf("expected the failure to be reported once:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestRunCaptures(t *testing.T) {
+	config := `
+containers:
+  auth.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: "admin token: (?P<token>\\w+)"
+          status: success
+  db.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          sequence: ["port (?P<port>\\d+)", "ready"]
+          status: success
+  app.local:
+    image: app:1
+    environment:
+      TOKEN: "${auth.local.captures.token}"
+      DATABASE: "postgres://db.local:${db.local.captures.port}/app"
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: started
+          status: success
+    depends_on:
+      - auth.local
+      - db.local
+`
+	fixture := `
+images:
+  app:1:
+    app: {}
+pods:
+  auth.local:
+    steps:
+      - stdout: "Generated admin token: abc123"
+  db.local:
+    steps:
+      - stdout: listening on port 5432
+      - stdout: ready
+  app.local:
+    steps:
+      - env: TOKEN
+      - env: DATABASE
+      - stdout: started
+`
+	project := newTestProject(t, config, fixture)
+	output := project.run(0)
+	assertContains(t, output, `\[app\.local\].*abc123`, `\[app\.local\].*postgres://db\.local:5432/app`)
+
+	// only the containers we depend on can be referenced
+	project = newTestProject(t, strings.Replace(config, "      - db.local\n", "", 1), fixture)
+	output = project.run(1)
+	assertContains(t, output, `app\.local references \$\{db\.local\.captures\.port\} but does not depend on db\.local`)
+}
+
+func TestRunLiveness(t *testing.T) {
+	config := `
+containers:
+  web.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready
+          status: success
+    liveness:
+      output:
+        - source: STDERR
+          regex: FATAL
+          status: failure
+      action: %s
+`
+	fixture := `
+images:
+  app:1:
+    app: {}
+pods:
+  web.local:
+    steps:
+      - stdout: ready
+      - delay: 0.5
+      - stderr: "FATAL: out of memory"
+`
+	// in the foreground a container that fails its liveness conditions fails the run
+	project := newTestProject(t, fmt.Sprintf(config, "fail"), fixture)
+	output := project.run(1, "--foreground")
+	assertContains(t, output, `web\.local is no longer live: STDERR matched FATAL\. Specified as failure`)
+	assertBefore(t, output, "Running in the foreground", "is no longer live")
+
+	// liveness is only checked in the foreground
+	project = newTestProject(t, fmt.Sprintf(config, "fail"), fixture)
+	output = project.run(0)
+	if strings.Contains(output, "no longer live") {
+		t.Errorf("liveness was checked without --foreground:\n%s", output)
+	}
+}
+
+func TestRunRestart(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		fixture  string
+		args     []string
+		restarts int
+		patterns []string
+	}{
+		{
+			name: "during startup",
+			config: `
+containers:
+  app.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready
+          status: success
+      timeout:
+        duration: 1
+        status: failure
+    restart:
+      policy: on-failure
+      max_attempts: 1`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - stdout: booting`,
+			restarts: 1,
+			patterns: []string{`Restarting in 1s \(attempt 1 of 1\): Hit Timeout`, `app\.local failed: Hit Timeout`},
+		},
+		{
+			name: "turned off",
+			config: `
+containers:
+  app.local:
+    image: app:1
+    state_conditions:
+      timeout:
+        duration: 1
+        status: failure
+    restart:
+      policy: on-failure
+      max_attempts: 0`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - stdout: booting`,
+			patterns: []string{`app\.local failed: Hit Timeout`},
+		},
+		{
+			name: "after exiting in the foreground",
+			config: `
+containers:
+  app.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready
+          status: success
+    restart:
+      policy: on-failure
+      max_attempts: 1
+    liveness:
+      action: fail`,
+			fixture: `
+pods:
+  app.local:
+    steps:
+      - stdout: ready
+      - delay: 0.3
+    exit_code: 2`,
+			args:     []string{"--foreground"},
+			restarts: 1,
+			patterns: []string{`Restarting in 1s \(attempt 1 of 1\): Exit code 2`, `app\.local is no longer live: Exit code 2`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			project := newTestProject(t, test.config, "images:\n  app:1:\n    app: {}\n"+test.fixture)
+			output := project.run(1, test.args...)
+			assertContains(t, output, test.patterns...)
+			if restarts := strings.Count(output, "Restarting in"); restarts != test.restarts {
+				t.Errorf("restarted %d times, expected %d:\n%s", restarts, test.restarts, output)
+			}
+		})
+	}
+}
+
+func TestRunOverrides(t *testing.T) {
+	config := `
+containers:
+  db.local:
+    image: app:1
+    environment:
+      LEVEL: info
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready
+          status: success
+  seed.tmp:
+    image: app:1
+    state_conditions:
+      exit:
+        codes: [0]
+        status: success
+    depends_on:
+      - db.local
+`
+	fixture := `
+images:
+  app:1:
+    app: {}
+pods:
+  db.local:
+    steps:
+      - env: LEVEL
+      - stdout: ready
+  seed.tmp:
+    steps:
+      - stdout: seeding
+    exit_code: 0
+  mail.local:
+    steps:
+      - stdout: mail started
+`
+	project := newTestProject(t, config, fixture)
+	project.write("local.yml", `
+containers:
+  db.local:
+    environment:
+      LEVEL: debug
+  seed.tmp: null
+  mail.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: started
+          status: success
+`)
+	output := project.run(0, "-c", "local.yml")
+	assertContains(t, output, `\[db\.local\].*debug`, `STDOUT matched started`)
+	if strings.Contains(output, "seeding") {
+		t.Errorf("a container removed by an override file was run:\n%s", output)
+	}
+
+	// problems in an override file are reported against it
+	project.write("broken.yml", `
+containers:
+  db.local:
+    depends_on+: [missing.local]
+`)
+	output = project.run(1, "-c", "broken.yml")
+	assertContains(t, output, `broken\.yml:4: container db\.local depends on missing\.local which does not exist in the config`)
+}
+
+func TestRunNamedContainers(t *testing.T) {
+	config := `
+containers:
+  db.local:
+    image: app:1
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: started
+          status: success
+  api.app.local:
+    image: app:1
+    depends_on:
+      - db.local
+  debug.local:
+    image: app:1
+    profiles: [debug]
+    depends_on:
+      - db.local
+  mail.local:
+    image: app:1
+`
+	fixture := `
+images:
+  app:1:
+    app: {}
+pods:
+  db.local:
+    steps:
+      - stdout: db started
+  api.app.local:
+    steps:
+      - stdout: api started
+  debug.local:
+    steps:
+      - stdout: debug started
+  mail.local:
+    steps:
+      - stdout: mail started
+`
+	tests := []struct {
+		name    string
+		args    []string
+		run     []string
+		skipped []string
+	}{
+		{
+			name:    "dependencies",
+			args:    []string{"api.app.local"},
+			run:     []string{"db.local", "api.app.local"},
+			skipped: []string{"debug.local", "mail.local"},
+		},
+		{
+			// a named container is run whatever its profiles are
+			name:    "profiles",
+			args:    []string{"debug.local"},
+			run:     []string{"db.local", "debug.local"},
+			skipped: []string{"api.app.local", "mail.local"},
+		},
+		{
+			name:    "profiles given",
+			args:    []string{"--profile", "debug", "mail.local"},
+			run:     []string{"mail.local"},
+			skipped: []string{"db.local", "api.app.local", "debug.local"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			project := newTestProject(t, config, fixture)
+			output := project.run(0, test.args...)
+			for _, name := range test.run {
+				assertContains(t, output, regexp.QuoteMeta("["+name+"]"))
+			}
+			for _, name := range test.skipped {
+				if strings.Contains(output, "["+name+"]") {
+					t.Errorf("%s was run:\n%s", name, output)
+				}
+			}
+		})
+	}
+
+	project := newTestProject(t, config, fixture)
+	output, code := project.constellation("run", "api.local")
+	if code == 0 {
+		t.Errorf("run of a container that is not in the config succeeded:\n%s", output)
+	}
+	assertContains(t, output, `Container api\.local is not in the config`)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dansteen/constellation/container"
+)
+
+// startContainers will start the provided containers as soon as all of the containers they depend on have started successfully.
+// Containers that do not depend on each other are started concurrently, with at most maxParallel containers being started at
+// any one time (0 means no limit).  order should be the order returned by Config.DependencyOrder and is used to decide
+// which container goes first when more are ready than we are allowed to start.  Once a container fails no more containers are
+// started, and we return every failure once the containers already starting have finished.
+func startContainers(containers map[string]*container.Container, order []string, maxParallel int, start func(*container.Container) error) error {
+	// the number of dependencies each container is still waiting on
+	waiting := make(map[string]int)
+	// the containers that are waiting on each container
+	dependents := make(map[string][]string)
+	for _, name := range order {
+		waiting[name] = len(containers[name].DependsOn)
+		for depName, _ := range containers[name].DependsOn {
+			dependents[depName] = append(dependents[depName], name)
+		}
+	}
+
+	// a place to report our results
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result)
+
+	started := make(map[string]bool)
+	running := 0
+	failures := make([]string, 0)
+	for {
+		// start everything that is ready to go
+		for _, name := range order {
+			if len(failures) > 0 || (maxParallel > 0 && running >= maxParallel) {
+				break
+			}
+			if started[name] || waiting[name] > 0 {
+				continue
+			}
+			started[name] = true
+			running++
+			go func(name string) {
+				results <- result{name: name, err: start(containers[name])}
+			}(name)
+		}
+
+		// if nothing is running, there is nothing left that we can start
+		if running == 0 {
+			break
+		}
+
+		// wait for something to finish
+		finished := <-results
+		running--
+		if finished.err != nil {
+			failures = append(failures, fmt.Sprintf("%s failed: %s", finished.name, finished.err))
+			continue
+		}
+		// let anything that depends on this container know it is done
+		for _, dependent := range dependents[finished.name] {
+			waiting[dependent]--
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "\n"))
+	}
+
+	// if anything was never started its dependencies could never be satisfied
+	notStarted := make([]string, 0)
+	for _, name := range order {
+		if !started[name] {
+			notStarted = append(notStarted, name)
+		}
+	}
+	if len(notStarted) > 0 {
+		return errors.New(fmt.Sprintf("The dependencies of %s could not be satisfied", strings.Join(notStarted, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dansteen/constellation/container"
+)
+
+// testContainers builds containers from a map of each container name to the names it depends on
+func testContainers(dependencies map[string][]string) map[string]*container.Container {
+	containers := make(map[string]*container.Container)
+	for name := range dependencies {
+		containers[name] = &container.Container{Name: name, DependsOn: make(map[string]*container.Container)}
+	}
+	for name, depNames := range dependencies {
+		for _, depName := range depNames {
+			containers[name].DependsOn[depName] = containers[depName]
+		}
+	}
+	return containers
+}
+
+// startRecorder records the order containers are started and finished in, and how many were starting at once
+type startRecorder struct {
+	lock       sync.Mutex
+	events     []string
+	running    int
+	maxRunning int
+	delay      time.Duration
+	failures   map[string]bool
+}
+
+func (recorder *startRecorder) start(ourContainer *container.Container) error {
+	recorder.lock.Lock()
+	recorder.events = append(recorder.events, "start "+ourContainer.Name)
+	recorder.running++
+	if recorder.running > recorder.maxRunning {
+		recorder.maxRunning = recorder.running
+	}
+	recorder.lock.Unlock()
+
+	time.Sleep(recorder.delay)
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.running--
+	recorder.events = append(recorder.events, "done "+ourContainer.Name)
+	if recorder.failures[ourContainer.Name] {
+		return errors.New("condition failed")
+	}
+	return nil
+}
+
+// index returns where event happened, or -1 if it didn't
+func (recorder *startRecorder) index(event string) int {
+	for index, recorded := range recorder.events {
+		if recorded == event {
+			return index
+		}
+	}
+	return -1
+}
+
+func TestStartContainersMaxParallel(t *testing.T) {
+	dependencies := map[string][]string{"a": nil, "b": nil, "c": nil, "d": nil, "e": nil}
+	order := []string{"a", "b", "c", "d", "e"}
+	for _, maxParallel := range []int{1, 2, 0} {
+		t.Run(fmt.Sprintf("max-parallel %d", maxParallel), func(t *testing.T) {
+			recorder := &startRecorder{delay: 50 * time.Millisecond}
+			err := startContainers(testContainers(dependencies), order, maxParallel, recorder.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := maxParallel
+			if maxParallel == 0 {
+				expected = len(order)
+			}
+			if recorder.maxRunning != expected {
+				t.Errorf("%d containers were started at once, expected %d", recorder.maxRunning, expected)
+			}
+			if len(recorder.events) != 2*len(order) {
+				t.Errorf("not every container was started: %v", recorder.events)
+			}
+		})
+	}
+}
+
+func TestStartContainersWaitsForDependencies(t *testing.T) {
+	dependencies := map[string][]string{
+		"db":      nil,
+		"cache":   nil,
+		"migrate": {"db"},
+		"app":     {"migrate", "cache"},
+	}
+	order := []string{"cache", "db", "migrate", "app"}
+	recorder := &startRecorder{delay: 20 * time.Millisecond}
+	err := startContainers(testContainers(dependencies), order, 0, recorder.start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, depNames := range dependencies {
+		for _, depName := range depNames {
+			if recorder.index("done "+depName) > recorder.index("start "+name) {
+				t.Errorf("%s was started before %s succeeded: %v", name, depName, recorder.events)
+			}
+		}
+	}
+	// containers that do not depend on each other start together
+	if recorder.index("start db") > recorder.index("done cache") {
+		t.Errorf("db waited for cache: %v", recorder.events)
+	}
+}
+
+func TestStartContainersStopsOnFailure(t *testing.T) {
+	dependencies := map[string][]string{
+		"db":    nil,
+		"cache": nil,
+		"app":   {"db"},
+	}
+	order := []string{"cache", "db", "app"}
+	recorder := &startRecorder{delay: 20 * time.Millisecond, failures: map[string]bool{"db": true, "cache": true}}
+	err := startContainers(testContainers(dependencies), order, 0, recorder.start)
+	if err == nil {
+		t.Fatal("expected the failures to be returned")
+	}
+	// every failure is reported, and once
+	for _, name := range []string{"db", "cache"} {
+		if strings.Count(err.Error(), name+" failed: condition failed") != 1 {
+			t.Errorf("expected one failure for %s in %q", name, err)
+		}
+	}
+	if recorder.index("start app") >= 0 {
+		t.Errorf("app was started even though db failed: %v", recorder.events)
+	}
+}
+
+func TestStartContainersUnsatisfiable(t *testing.T) {
+	containers := testContainers(map[string][]string{"db": nil, "app": {"db"}})
+	// a dependency that is not in our order will never be started
+	err := startContainers(containers, []string{"app"}, 0, (&startRecorder{}).start)
+	if err == nil || !strings.Contains(err.Error(), "The dependencies of app could not be satisfied") {
+		t.Errorf("expected app to be reported as unsatisfiable, got %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dansteen/constellation/config"
+	"github.com/dansteen/constellation/util"
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON Schema describing constellation config files",
+	Long: `Print a JSON Schema (draft-07) describing constellation config files.  This can be used by editors to
+autocomplete and validate config files, e.g.:
+
+  constellation schema > constellation.schema.json`,
+	Run: schema,
+}
+
+func init() {
+	RootCmd.AddCommand(schemaCmd)
+}
+
+func schema(cmd *cobra.Command, args []string) {
+	data, err := json.MarshalIndent(config.Schema(), "", "  ")
+	util.Check(err)
+	fmt.Println(string(data))
+}
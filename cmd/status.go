@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dansteen/constellation/config"
+	"github.com/dansteen/constellation/container"
+	"github.com/dansteen/constellation/util"
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Aliases: []string{"ps"},
+	Short:   "Show the state of the containers specified in the supplied config file",
+	Long:    ``,
+	Run:     status,
+}
+
+func init() {
+	RootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringP("output", "o", "table", "The format to print the status in.  One of table, json or yaml")
+
+	viper.BindPFlag("output", statusCmd.Flags().Lookup("output"))
+}
+
+// containerStatus holds the status of a single container
+type containerStatus struct {
+	Name      string       `json:"name"`
+	UUID      string       `json:"uuid"`
+	State     string       `json:"state"`
+	IPs       []string     `json:"ips"`
+	StartedAt *time.Time   `json:"started_at,omitempty"`
+	Ports     []portStatus `json:"ports"`
+	Transient bool         `json:"transient"`
+	// Result is the result of the state conditions of the last run of this container
+	Result string `json:"result"`
+	// Completed is set when a transient container has exited after its state conditions reported success
+	Completed bool `json:"completed"`
+}
+
+// portStatus holds the host port mapping of a container port
+type portStatus struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Port     int    `json:"port"`
+	HostPort int    `json:"host_port"`
+}
+
+func status(cmd *cobra.Command, args []string) {
+	BaseInit()
+	// get some config items
+	projectName := viper.GetString("projectName")
+	netConfigPath := viper.GetString("netConfigPath")
+	constellationFile, overrideFiles := GetConfigFiles()
+	includeDirs := viper.GetStringSlice("includeDirs")
+	output := viper.GetString("output")
+	rt := GetRuntime()
+
+	// process our configs
+	configData := config.ProcessFile(constellationFile, overrideFiles, includeDirs, GetVariables())
+	order, err := configData.DependencyOrder()
+	util.Check(err)
+
+	// get all of our pods
+	allPods, err := rt.GetAllPods(projectName)
+	util.Check(err)
+
+	statuses := make([]containerStatus, 0)
+	for _, name := range order {
+		ourContainer := configData.Containers[name]
+		appName, err := rt.GetAppName(projectName, name)
+		util.Check(err)
+		record, err := container.LoadRecord(netConfigPath, name)
+		util.Check(err)
+
+		status := containerStatus{
+			Name:      name,
+			State:     "not started",
+			IPs:       make([]string, 0),
+			Ports:     make([]portStatus, 0),
+			Transient: ourContainer.Transient(),
+			Result:    record.Result,
+		}
+		if pod, ok := allPods.Pods[appName]; ok {
+			status.UUID = pod.Name
+			status.State = pod.State
+			startedAt := pod.StartedAt
+			status.StartedAt = &startedAt
+			for _, network := range pod.Networks {
+				status.IPs = append(status.IPs, network.IP)
+			}
+			status.Completed = status.Transient && pod.State == "exited" && record.Result == "success"
+		}
+		for _, port := range record.Ports {
+			status.Ports = append(status.Ports, portStatus{
+				Name:     port.Name,
+				Protocol: port.Protocol,
+				Port:     port.Port,
+				HostPort: port.HostPort,
+			})
+		}
+		statuses = append(statuses, status)
+	}
+
+	switch output {
+	case "table":
+		printStatusTable(statuses)
+	case "json":
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		util.Check(err)
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(statuses)
+		util.Check(err)
+		fmt.Print(string(data))
+	default:
+		util.Check(errors.New(fmt.Sprintf("--output must be one of table, json or yaml.  Got %s", output)))
+	}
+}
+
+// printStatusTable prints our statuses as a table
+func printStatusTable(statuses []containerStatus) {
+	address := util.GetDefaultIP()
+	output := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(output, "NAME\tUUID\tSTATE\tIPS\tSTARTED\tPORTS\tRESULT")
+	for _, status := range statuses {
+		started := ""
+		if status.StartedAt != nil {
+			started = status.StartedAt.Format(time.RFC3339)
+		}
+		ports := make([]string, 0)
+		for _, port := range status.Ports {
+			ports = append(ports, fmt.Sprintf("%s->%s:%d", port.Name, address, port.HostPort))
+		}
+		result := status.Result
+		if status.Completed {
+			result = "completed"
+		}
+		fmt.Fprintf(output, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", status.Name, status.UUID, status.State, strings.Join(status.IPs, ","), started, strings.Join(ports, ","), result)
+	}
+	output.Flush()
+}
@@ -16,12 +16,8 @@ package cmd
 
 import (
 	"fmt"
-	"log"
-	"os/exec"
-	"strings"
 
 	//"github.com/davecgh/go-spew/spew"
-	"github.com/dansteen/constellation/rkt"
 	"github.com/dansteen/constellation/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -55,23 +51,8 @@ func stop(cmd *cobra.Command, args []string) {
 	BaseInit()
 	// get some config items
 	projectName := viper.GetString("projectName")
+	rt := GetRuntime()
 
-	// get our running containers for this project
-	runningPods, err := rkt.GetRunningPods(projectName)
-	util.Check(err)
-
-	// run through and stop them
-	for name, pod := range runningPods.Pods {
-		log.Println(name)
-
-		// stop the container
-		command := strings.Split(fmt.Sprintf("rkt stop --force %s", pod.Name), " ")
-		log.Printf("Running: %+v", command)
-		stopCmd := exec.Command(command[0], command[1:]...)
-		output, err := stopCmd.CombinedOutput()
-		log.Printf("%s", output)
-		util.Check(err)
-
-		log.Printf("Stopped %s", name)
-	}
+	// stop our running containers for this project
+	util.Check(stopProject(rt, projectName, []string{}, false))
 }
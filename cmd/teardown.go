@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/types"
+)
+
+// stopProject will stop all of the running pods of a project.  Pods are stopped in the reverse of order (normally the order
+// returned by Config.DependencyOrder) so that containers are stopped before the containers they depend on.  Any pods of the
+// project that are not named in order are stopped last.  If remove is set the pods are removed once stopped.
+func stopProject(rt runtime.Runtime, projectName string, order []string, remove bool) error {
+	// get all of the pods for this project
+	allPods, err := rt.GetAllPods(projectName)
+	if err != nil {
+		return err
+	}
+
+	// work out the order to stop pods in
+	podOrder := make([]string, 0)
+	for index := len(order) - 1; index >= 0; index-- {
+		appName, err := rt.GetAppName(projectName, order[index])
+		if err != nil {
+			return err
+		}
+		if _, ok := allPods.Pods[appName]; ok {
+			podOrder = append(podOrder, appName)
+		}
+	}
+	for appName, _ := range allPods.Pods {
+		if !contains(podOrder, appName) {
+			podOrder = append(podOrder, appName)
+		}
+	}
+
+	for _, appName := range podOrder {
+		err = stopPod(rt, appName, allPods.Pods[appName], remove)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanProject will stop and remove all of the pods of a project, and then remove the network and config files of the project
+func cleanProject(rt runtime.Runtime, projectName string, netConfigPath string, order []string) error {
+	err := stopProject(rt, projectName, order, true)
+	if err != nil {
+		return err
+	}
+
+	// remove the network
+	log.Println("Removing network")
+	err = rt.RemoveNetwork(projectName, netConfigPath)
+	if err != nil {
+		return err
+	}
+
+	// remove the config files
+	log.Println("Removing config files")
+	return os.RemoveAll(netConfigPath)
+}
+
+// stopContainer will stop the pod of a single container of a project if it is running
+func stopContainer(rt runtime.Runtime, projectName string, name string) error {
+	allPods, err := rt.GetAllPods(projectName)
+	if err != nil {
+		return err
+	}
+	appName, err := rt.GetAppName(projectName, name)
+	if err != nil {
+		return err
+	}
+	if pod, ok := allPods.Pods[appName]; ok {
+		return stopPod(rt, appName, pod, false)
+	}
+	return nil
+}
+
+// stopPod will stop a pod if it is running, and remove it if requested
+func stopPod(rt runtime.Runtime, appName string, pod types.Pod, remove bool) error {
+	if pod.State == "running" {
+		err := rt.Stop(pod)
+		if err != nil {
+			return err
+		}
+		log.Printf("Stopped %s", appName)
+	}
+	if remove {
+		err := rt.Remove(pod)
+		if err != nil {
+			return err
+		}
+		log.Printf("Removed %s", appName)
+	}
+	return nil
+}
+
+// contains checks if value is in list
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// onFailurePolicies are the values accepted by --on-failure
+var onFailurePolicies = []string{"leave", "stop", "clean"}
+
+// handleFailure will apply the failure policy provided by --on-failure once a container has failed to start
+func handleFailure(rt runtime.Runtime, policy string, projectName string, netConfigPath string, order []string) error {
+	switch policy {
+	case "stop":
+		log.Printf("Stopping the containers of project %s", projectName)
+		return stopProject(rt, projectName, order, false)
+	case "clean":
+		log.Printf("Stopping and removing the containers of project %s", projectName)
+		return cleanProject(rt, projectName, netConfigPath, order)
+	}
+	log.Printf("Leaving the containers of project %s in place", projectName)
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright © 2016 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dansteen/constellation/config"
+	"github.com/dansteen/constellation/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate [file...]",
+	Short: "Check constellation files for problems without running anything",
+	Long: `Check the supplied constellation files (or the one passed with -c, along with any override files passed with
+further -c flags) and any files they require for problems.
+Unknown keys, invalid values, missing images, dependencies on containers that do not exist, file monitors on paths that
+are not mounted and dependency cycles are all reported along with the file and line they are on.  No container runtime
+is needed.`,
+	Run: validate,
+}
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+}
+
+func validate(cmd *cobra.Command, args []string) {
+	BaseInit()
+	includeDirs := viper.GetStringSlice("includeDirs")
+
+	// each file we are given is checked on its own, and the files passed with -c are checked together
+	type check struct {
+		file      string
+		overrides []string
+	}
+	checks := make([]check, 0)
+	for _, file := range args {
+		checks = append(checks, check{file: file})
+	}
+	if len(checks) == 0 {
+		file, overrides := GetConfigFiles()
+		if file != "" {
+			checks = append(checks, check{file: file, overrides: overrides})
+		}
+	}
+	if len(checks) == 0 {
+		util.Check(errors.New("No constellation file provided.  Pass one with -c or as an argument"))
+	}
+
+	variables := GetVariables()
+	failed := false
+	for _, item := range checks {
+		_, err := config.LoadFile(item.file, item.overrides, includeDirs, variables)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		} else {
+			fmt.Printf("%s: ok\n", strings.Join(append([]string{item.file}, item.overrides...), " + "))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"encoding/json"
 
@@ -15,7 +16,17 @@ import (
 type Config struct {
 	Containers map[string]*container.Container `json:"containers"`
 	Requires   []string                        `json:"require"`
-	Volumes    map[string]types.Volume         `json"volumes"`
+	Volumes    map[string]types.Volume         `json:"volumes"`
+	// Variables are substituted into the config when it is loaded
+	Variables map[string]string `json:"variables"`
+
+	// containerSources and volumeSources record which file each container and volume came from
+	containerSources map[string]source
+	volumeSources    map[string]source
+	// containerDefinitions and volumeDefinitions hold the raw definition of each container and volume, which is what
+	// extends and override files are merged into
+	containerDefinitions map[string]map[string]interface{}
+	volumeDefinitions    map[string]map[string]interface{}
 }
 
 // UnmarshalJSON
@@ -24,7 +35,8 @@ func (config *Config) UnmarshalJSON(b []byte) error {
 	type TempConfig struct {
 		Containers map[string]*container.Container `json:"containers"`
 		Requires   []string                        `json:"require"`
-		Volumes    map[string]types.Volume         `json"volumes"`
+		Volumes    map[string]types.Volume         `json:"volumes"`
+		Variables  map[string]string               `json:"variables"`
 	}
 	var tempConfig TempConfig
 	// unmarshal our items into the container
@@ -35,6 +47,9 @@ func (config *Config) UnmarshalJSON(b []byte) error {
 
 	// add the names into each container
 	for name, container := range tempConfig.Containers {
+		if container == nil {
+			return errors.New(fmt.Sprintf("container %s has no definition", name))
+		}
 		// we have to do this a bit round-aboutly do to https://github.com/golang/go/issues/3117
 		container.Name = name
 		tempConfig.Containers[name] = container
@@ -50,11 +65,15 @@ func (config *Config) UnmarshalJSON(b []byte) error {
 	config.Containers = tempConfig.Containers
 	config.Requires = tempConfig.Requires
 	config.Volumes = tempConfig.Volumes
+	config.Variables = tempConfig.Variables
 	return nil
 }
 
 // Merge will merge two configs, but not overwrite existing data
 func (config Config) Merge(newConfig Config) Config {
+	// make sure we have somewhere to merge into
+	config.init()
+
 	// run through the new continers
 	for name, container := range newConfig.Containers {
 		// make sure we arent overwriting existing values
@@ -63,6 +82,8 @@ func (config Config) Merge(newConfig Config) Config {
 		} else {
 			// do the merge
 			config.Containers[name] = container
+			config.containerSources[name] = newConfig.containerSources[name]
+			config.containerDefinitions[name] = newConfig.containerDefinitions[name]
 		}
 	}
 
@@ -74,6 +95,15 @@ func (config Config) Merge(newConfig Config) Config {
 		} else {
 			// do the merge
 			config.Volumes[name] = volume
+			config.volumeSources[name] = newConfig.volumeSources[name]
+			config.volumeDefinitions[name] = newConfig.volumeDefinitions[name]
+		}
+	}
+
+	// variables have already been substituted in, so these are also just for completion
+	for name, value := range newConfig.Variables {
+		if _, ok := config.Variables[name]; !ok {
+			config.Variables[name] = value
 		}
 	}
 
@@ -82,6 +112,31 @@ func (config Config) Merge(newConfig Config) Config {
 	return config
 }
 
+// init will make sure all of our maps exist
+func (config *Config) init() {
+	if config.Containers == nil {
+		config.Containers = make(map[string]*container.Container)
+	}
+	if config.Volumes == nil {
+		config.Volumes = make(map[string]types.Volume)
+	}
+	if config.Variables == nil {
+		config.Variables = make(map[string]string)
+	}
+	if config.containerSources == nil {
+		config.containerSources = make(map[string]source)
+	}
+	if config.volumeSources == nil {
+		config.volumeSources = make(map[string]source)
+	}
+	if config.containerDefinitions == nil {
+		config.containerDefinitions = make(map[string]map[string]interface{})
+	}
+	if config.volumeDefinitions == nil {
+		config.volumeDefinitions = make(map[string]map[string]interface{})
+	}
+}
+
 // DependencyOrder build a sorted list of containers based on each containers dependencies.
 // We use a topological sort for this.  Circular dependencies result in an error
 func (config *Config) DependencyOrder() ([]string, error) {
@@ -90,16 +145,16 @@ func (config *Config) DependencyOrder() ([]string, error) {
 	// a place to store our nodes
 	nodes := make(map[string]graph.Node)
 
-	// add in nodes for each of our containers
-	for name, _ := range config.Containers {
+	// add in nodes for each of our containers.  We go through them in order so that we always get the same result
+	for _, name := range sortedNames(config.Containers) {
 		nodes[name] = ourGraph.MakeNode()
 		// hook the data back into the graph (not strictly required)
 		*nodes[name].Value = name
 	}
 
 	// add in our edges
-	for name, container := range config.Containers {
-		for _, dep := range container.DependsStrings {
+	for _, name := range sortedNames(config.Containers) {
+		for _, dep := range config.Containers[name].DependsStrings {
 			// make sure the dependency exists
 			if _, found := nodes[dep]; !found {
 				return make([]string, 0), errors.New(fmt.Sprintf("Container %v depends on %v which is not included in the config\n", name, dep))
@@ -109,6 +164,11 @@ func (config *Config) DependencyOrder() ([]string, error) {
 		}
 	}
 
+	// a topological sort of a graph with cycles in it is meaningless
+	if cycles := config.cycles(); len(cycles) > 0 {
+		return make([]string, 0), errors.New(fmt.Sprintf("Dependency cycle between %s\n", strings.Join(cycles[0], ", ")))
+	}
+
 	// do our sort
 	sorted := ourGraph.TopologicalSort()
 
@@ -120,3 +180,40 @@ func (config *Config) DependencyOrder() ([]string, error) {
 
 	return orderedContainerNames, nil
 }
+
+// Only will remove every container from the config other than those in names and the containers they depend on, directly
+// or through other containers
+func (config *Config) Only(names []string) error {
+	// make sure our dependencies make sense before we follow them
+	_, err := config.DependencyOrder()
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if keep[name] {
+			return
+		}
+		keep[name] = true
+		for _, dep := range config.Containers[name].DependsStrings {
+			visit(dep)
+		}
+	}
+	for _, name := range names {
+		if _, ok := config.Containers[name]; !ok {
+			return errors.New(fmt.Sprintf("Container %s is not in the config", name))
+		}
+		visit(name)
+	}
+
+	for name := range config.Containers {
+		if !keep[name] {
+			delete(config.Containers, name)
+			delete(config.containerSources, name)
+			delete(config.containerDefinitions, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOnly(t *testing.T) {
+	files := map[string]string{"constellation.yml": `
+containers:
+  db.local:
+    image: postgres:10
+  cache.local:
+    image: redis:5
+  api.migrate.tmp:
+    image: api:1
+    depends_on: [db.local]
+  api.app.local:
+    image: api:1
+    depends_on: [api.migrate.tmp, cache.local]
+  mail.local:
+    image: mailhog:1
+`}
+
+	tests := []struct {
+		name       string
+		names      []string
+		containers []string
+		err        string
+	}{
+		{
+			name:       "no dependencies",
+			names:      []string{"mail.local"},
+			containers: []string{"mail.local"},
+		},
+		{
+			name:       "dependencies of dependencies",
+			names:      []string{"api.app.local"},
+			containers: []string{"api.app.local", "api.migrate.tmp", "cache.local", "db.local"},
+		},
+		{
+			name:       "several",
+			names:      []string{"api.migrate.tmp", "mail.local"},
+			containers: []string{"api.migrate.tmp", "db.local", "mail.local"},
+		},
+		{
+			name:       "shared dependencies",
+			names:      []string{"db.local", "api.migrate.tmp"},
+			containers: []string{"api.migrate.tmp", "db.local"},
+		},
+		{
+			name:  "unknown",
+			names: []string{"api.local"},
+			err:   "Container api.local is not in the config",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, problems := loadTestConfig(t, files, nil, nil)
+			assertProblems(t, problems)
+
+			err := config.Only(test.names)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := sortedNames(config.Containers); !reflect.DeepEqual(names, test.containers) {
+				t.Errorf("containers are %v, expected %v", names, test.containers)
+			}
+			// the order is still worked out from what is left
+			order, err := config.DependencyOrder()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(order) != len(test.containers) {
+				t.Errorf("dependency order %v does not have the containers that were kept", order)
+			}
+		})
+	}
+}
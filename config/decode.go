@@ -0,0 +1,239 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// prunedValue marks a part of a raw value that is to be left out
+var prunedValue = &struct{}{}
+
+// unmarshalerType is json.Unmarshaler.  Types that implement it decode themselves, so their keys don't have to match
+// their fields
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// decode will unmarshal the raw (unmarshalled into interface{}) value of part of a config file into target.  Parts of
+// value that can't be decoded are reported as problems against src and left out, so that the rest can still be checked.
+// path is where value is in its file.  We return value with those parts left out, and false if even that could not be
+// decoded.
+func decode(src source, value interface{}, target interface{}, path string) (interface{}, Problems, bool) {
+	err := unmarshalValue(value, target)
+	if err == nil {
+		return value, nil, true
+	}
+	// our target is looked into even if it decodes itself, since Config does that by its fields
+	targetValue := reflect.ValueOf(target).Elem()
+	problems, broken := decodeChildProblems(src, value, targetValue.Type(), path)
+	if len(problems) == 0 {
+		problems, broken = Problems{decodeProblem(src, path, err)}, []string{path}
+	}
+
+	// try again without the parts that are broken
+	for _, brokenPath := range broken {
+		relative := strings.TrimPrefix(strings.TrimPrefix(brokenPath, path), "/")
+		value = markPruned(value, strings.Split(relative, "/"))
+	}
+	value = removePruned(value)
+	targetValue.Set(reflect.Zero(targetValue.Type()))
+	err = unmarshalValue(value, target)
+	if err != nil {
+		return value, append(problems, decodeProblem(src, path, err)), false
+	}
+	return value, problems, true
+}
+
+// decodeProblems works out why value could not be decoded into valueType.  Each problem is reported against the deepest
+// part of value that fails to decode on its own, and the paths of those parts are returned so they can be left out.
+func decodeProblems(src source, value interface{}, valueType reflect.Type, path string) (Problems, []string) {
+	err := unmarshalValue(value, reflect.New(valueType).Interface())
+	if err == nil {
+		return nil, nil
+	}
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	// types that decode themselves are taken as a whole.  otherwise, if nothing inside of us is broken on its own, the
+	// problem is with us
+	problems, broken := make(Problems, 0), make([]string, 0)
+	if !reflect.PtrTo(valueType).Implements(unmarshalerType) {
+		problems, broken = decodeChildProblems(src, value, valueType, path)
+	}
+	if len(problems) == 0 {
+		return Problems{decodeProblem(src, path, err)}, []string{path}
+	}
+	return problems, broken
+}
+
+// decodeChildProblems is decodeProblems for each of the keys or items of value
+func decodeChildProblems(src source, value interface{}, valueType reflect.Type, path string) (Problems, []string) {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	problems := make(Problems, 0)
+	broken := make([]string, 0)
+	addProblems := func(itemProblems Problems, itemBroken []string) {
+		problems = append(problems, itemProblems...)
+		broken = append(broken, itemBroken...)
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		switch valueType.Kind() {
+		case reflect.Map:
+			for _, key := range sortedKeys(typed) {
+				itemPath := joinPath(path, key)
+				// hashes of definitions can't have empty ones
+				if typed[key] == nil && valueType.Elem().Kind() == reflect.Ptr {
+					problems = append(problems, src.problem(itemPath, "%s has no definition", describePath(itemPath)))
+					broken = append(broken, itemPath)
+					continue
+				}
+				addProblems(decodeProblems(src, typed[key], valueType.Elem(), itemPath))
+			}
+		case reflect.Struct:
+			fields := jsonFields(valueType)
+			for _, key := range sortedKeys(typed) {
+				if field, ok := fields[key]; ok {
+					addProblems(decodeProblems(src, typed[key], field.Type, joinPath(path, key)))
+				}
+			}
+		}
+	case []interface{}:
+		if valueType.Kind() == reflect.Slice {
+			for index, item := range typed {
+				addProblems(decodeProblems(src, item, valueType.Elem(), joinPath(path, strconv.Itoa(index))))
+			}
+		}
+	}
+	return problems, broken
+}
+
+// decodeProblem creates a problem for an error decoding the value at path.  Type errors are reported against the key
+// they are about.
+func decodeProblem(src source, path string, err error) Problem {
+	message := err.Error()
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		if typeErr.Field != "" {
+			path = joinPath(path, strings.Replace(typeErr.Field, ".", "/", -1))
+		}
+		// containers and volumes are named, anything else by its key
+		key := describePath(path)
+		if parts := strings.Split(path, "/"); len(parts) > 2 {
+			key = parts[len(parts)-1]
+		}
+		message = fmt.Sprintf("%s must be %s, not %s", key, yamlTypeName(typeErr.Type), yamlValueName(typeErr.Value))
+	}
+	if owner := describePath(path); owner != path && !strings.HasPrefix(message, owner) {
+		message = fmt.Sprintf("%s: %s", owner, message)
+	}
+	return src.problem(path, "%s", message)
+}
+
+// describePath names the container or volume that path is in, or just returns path if it isn't in one
+func describePath(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) >= 2 {
+		switch parts[0] {
+		case "containers":
+			return "container " + parts[1]
+		case "volumes":
+			return "volume " + parts[1]
+		}
+	}
+	return path
+}
+
+// yamlTypeName describes a go type the way someone writing yaml would think of it
+func yamlTypeName(valueType reflect.Type) string {
+	switch valueType.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	}
+	return "a hash"
+}
+
+// yamlValueName describes the kind of json value in an UnmarshalTypeError the way someone writing yaml would think of it
+func yamlValueName(value string) string {
+	switch {
+	case value == "bool":
+		return "true or false.  Quote it if it is meant to be a string"
+	case value == "array":
+		return "a list"
+	case value == "object":
+		return "a hash"
+	case strings.HasPrefix(value, "number"):
+		return "a number"
+	}
+	return "a " + value
+}
+
+// unmarshalValue runs a raw value through the same unmarshalling as a config file
+func unmarshalValue(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// markPruned returns a copy of value with the part at path replaced by prunedValue
+func markPruned(value interface{}, path []string) interface{} {
+	if len(path) == 0 || (len(path) == 1 && path[0] == "") {
+		return prunedValue
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		marked := make(map[string]interface{})
+		for key, item := range typed {
+			marked[key] = item
+		}
+		if item, ok := typed[path[0]]; ok {
+			marked[path[0]] = markPruned(item, path[1:])
+		}
+		return marked
+	case []interface{}:
+		marked := append([]interface{}{}, typed...)
+		if index, err := strconv.Atoi(path[0]); err == nil && index >= 0 && index < len(typed) {
+			marked[index] = markPruned(typed[index], path[1:])
+		}
+		return marked
+	}
+	return value
+}
+
+// removePruned returns a copy of value without anything that has been marked by markPruned
+func removePruned(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		kept := make(map[string]interface{})
+		for key, item := range typed {
+			if item != prunedValue {
+				kept[key] = removePruned(item)
+			}
+		}
+		return kept
+	case []interface{}:
+		kept := make([]interface{}, 0)
+		for _, item := range typed {
+			if item != prunedValue {
+				kept = append(kept, removePruned(item))
+			}
+		}
+		return kept
+	}
+	if value == prunedValue {
+		return nil
+	}
+	return value
+}
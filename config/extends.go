@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dansteen/constellation/container"
+)
+
+// extendedLists are the state conditions that are added to, rather than replaced, by a container that extends another
+var extendedLists = []string{"output", "filemonitor", "http", "tcp", "command", "all", "any"}
+
+// extendsResolver works out the definitions of containers that extend others
+type extendsResolver struct {
+	includeDirs []string
+	variables   map[string]string
+	// files holds the configs loaded for extends that name a file
+	files    map[string]*Config
+	problems Problems
+}
+
+// resolveExtends will replace each container that extends another with the result of merging its definition onto the
+// definition it extends.  This has to wait until all of our required files are loaded, since a container can extend
+// one defined in any of them.  variables are used to load the files that extends name.
+func (config *Config) resolveExtends(includeDirs []string, variables map[string]string) Problems {
+	resolver := extendsResolver{
+		includeDirs: includeDirs,
+		variables:   make(map[string]string),
+		files:       make(map[string]*Config),
+		problems:    make(Problems, 0),
+	}
+	for name, value := range config.Variables {
+		resolver.variables[name] = value
+	}
+	for name, value := range variables {
+		resolver.variables[name] = value
+	}
+
+	for _, name := range sortedNames(config.Containers) {
+		if config.Containers[name] == nil || config.Containers[name].Extends == nil {
+			continue
+		}
+		src := config.containerSources[name]
+		path := joinPath("containers", name+"/extends")
+		definition, err := resolver.definition(config, "", name, make([]string, 0))
+		if err != nil {
+			resolver.problems = append(resolver.problems, src.problem(path, "%s", err))
+			continue
+		}
+
+		// run the merged definition back through the same unmarshalling as everything else
+		extended := &container.Container{}
+		_, decodeProblems, decoded := decodeDefinition(src, definition, extended, joinPath("containers", name))
+		resolver.problems = append(resolver.problems, decodeProblems...)
+		if !decoded {
+			continue
+		}
+		extended.Name = name
+		config.Containers[name] = extended
+		// Validate leaves this to us, so that containers we could not resolve aren't also reported for it
+		if extended.Image == "" {
+			resolver.problems = append(resolver.problems, src.problem(joinPath("containers", name), "container %s has no image", name))
+		}
+	}
+	return resolver.problems
+}
+
+// definition returns the raw definition of the container name in config with everything it extends merged in.  file
+// is where config was loaded from ("" for our own config), and chain the containers we have passed through to get here.
+func (resolver *extendsResolver) definition(config *Config, file string, name string, chain []string) (map[string]interface{}, error) {
+	link := name
+	if file != "" {
+		link = fmt.Sprintf("%s (%s)", name, file)
+	}
+	for _, seen := range chain {
+		if seen == link {
+			return nil, errors.New(fmt.Sprintf("extends cycle: %s", strings.Join(append(chain, link), " -> ")))
+		}
+	}
+	chain = append(chain, link)
+
+	definition := config.containerDefinitions[name]
+	if config.Containers[name] == nil || config.Containers[name].Extends == nil {
+		return definition, nil
+	}
+	extends := config.Containers[name].Extends
+
+	// find the config our base is in
+	base, baseFile := config, file
+	if extends.File != "" {
+		base, baseFile = resolver.load(extends.File), extends.File
+	}
+	if _, ok := base.Containers[extends.Container]; !ok {
+		if extends.File != "" {
+			return nil, errors.New(fmt.Sprintf("container %s extends %s which does not exist in %s", name, extends.Container, extends.File))
+		}
+		return nil, errors.New(fmt.Sprintf("container %s extends %s which does not exist in the config", name, extends.Container))
+	}
+
+	baseDefinition, err := resolver.definition(base, baseFile, extends.Container, chain)
+	if err != nil {
+		return nil, err
+	}
+	return mergeDefinitions(baseDefinition, definition), nil
+}
+
+// load will load a file that containers are extended from.  Each file is only loaded once.
+func (resolver *extendsResolver) load(file string) *Config {
+	if config, ok := resolver.files[file]; ok {
+		return config
+	}
+	config, problems, _ := loadFile(file, resolver.includeDirs, resolver.variables)
+	resolver.problems = append(resolver.problems, problems...)
+	resolver.files[file] = &config
+	return &config
+}
+
+// mergeDefinitions merges the raw definition of a container onto the raw definition of the container it extends.
+// Environments and state conditions are merged key by key, mounts by path and depends_on by name.  Anything else set in
+// definition replaces what is in base.
+func mergeDefinitions(base map[string]interface{}, definition map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range definition {
+		switch key {
+		case "environment":
+			merged[key] = mergeMaps(merged[key], value, nil)
+		case "state_conditions":
+			merged[key] = mergeMaps(merged[key], value, extendedLists)
+		case "mounts":
+			merged[key] = mergeLists(merged[key], value, func(item interface{}) string {
+				if mount, ok := item.(map[string]interface{}); ok {
+					return fmt.Sprint(mount["path"])
+				}
+				return ""
+			})
+		case "depends_on":
+			merged[key] = mergeLists(merged[key], value, func(item interface{}) string {
+				return fmt.Sprint(item)
+			})
+		default:
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// mergeMaps sets each key of value in base.  Keys in lists hold lists that are added to rather than replaced.  If
+// either side is not a map, value wins
+func mergeMaps(base interface{}, value interface{}, lists []string) interface{} {
+	baseMap, ok := base.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	merged := make(map[string]interface{})
+	for key, item := range baseMap {
+		merged[key] = item
+	}
+	for key, item := range valueMap {
+		if contains(lists, key) {
+			merged[key] = mergeLists(merged[key], item, nil)
+		} else {
+			merged[key] = item
+		}
+	}
+	return merged
+}
+
+// mergeLists adds the items of value onto the end of base.  If key is provided, items of base with the same key as an
+// item of value are dropped.  If either side is not a list, value wins
+func mergeLists(base interface{}, value interface{}, key func(interface{}) string) interface{} {
+	baseList, ok := base.([]interface{})
+	if !ok {
+		return value
+	}
+	valueList, ok := value.([]interface{})
+	if !ok {
+		return value
+	}
+
+	replaced := make(map[string]bool)
+	if key != nil {
+		for _, item := range valueList {
+			replaced[key(item)] = true
+		}
+	}
+	merged := make([]interface{}, 0)
+	for _, item := range baseList {
+		if key == nil || !replaced[key(item)] {
+			merged = append(merged, item)
+		}
+	}
+	return append(merged, valueList...)
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+// yamlValue unmarshals a yaml document the way config files are before they are merged
+func yamlValue(t *testing.T, document string) map[string]interface{} {
+	var value map[string]interface{}
+	err := yaml.Unmarshal([]byte(document), &value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// assertValue fails the test if value is not the same as the yaml document expected
+func assertValue(t *testing.T, value interface{}, expected string) {
+	t.Helper()
+	if !reflect.DeepEqual(value, yamlValue(t, expected)) {
+		got, _ := json.Marshal(value)
+		want, _ := json.Marshal(yamlValue(t, expected))
+		t.Errorf("got %s, expected %s", got, want)
+	}
+}
+
+func TestMergeDefinitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		base       string
+		definition string
+		expected   string
+	}{
+		{
+			name:       "replaced",
+			base:       "{image: api:1, exec: ./serve, profiles: [dev]}",
+			definition: "{exec: ./migrate, profiles: [test]}",
+			expected:   "{image: api:1, exec: ./migrate, profiles: [test]}",
+		},
+		{
+			name:       "environment",
+			base:       "{environment: {DB_HOST: db.local, LEVEL: info}}",
+			definition: "{environment: {LEVEL: debug, MODE: migrate}}",
+			expected:   "{environment: {DB_HOST: db.local, LEVEL: debug, MODE: migrate}}",
+		},
+		{
+			name:       "mounts",
+			base:       "{mounts: [{volume: data, path: /data}, {volume: logs, path: /logs}]}",
+			definition: "{mounts: [{volume: other, path: /logs}, {volume: tmp, path: /tmp}]}",
+			expected:   "{mounts: [{volume: data, path: /data}, {volume: other, path: /logs}, {volume: tmp, path: /tmp}]}",
+		},
+		{
+			name:       "depends_on",
+			base:       "{depends_on: [db.local, cache.local]}",
+			definition: "{depends_on: [cache.local, queue.local]}",
+			expected:   "{depends_on: [db.local, cache.local, queue.local]}",
+		},
+		{
+			name: "state_conditions",
+			base: `{state_conditions: {output: [{source: STDOUT, regex: ready, status: success}],
+				timeout: {duration: 30, status: failure}, not: {tcp: [{port: admin, status: success}]}}}`,
+			definition: `{state_conditions: {output: [{source: STDERR, regex: panic, status: failure}],
+				timeout: {duration: 5, status: failure}}}`,
+			expected: `{state_conditions: {output: [{source: STDOUT, regex: ready, status: success}, {source: STDERR, regex: panic, status: failure}],
+				timeout: {duration: 5, status: failure}, not: {tcp: [{port: admin, status: success}]}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged := mergeDefinitions(yamlValue(t, test.base), yamlValue(t, test.definition))
+			assertValue(t, merged, test.expected)
+		})
+	}
+}
+
+func TestExtends(t *testing.T) {
+	files := map[string]string{
+		"constellation.yml": `
+require:
+  - required.yml
+containers:
+  api.migrate.tmp:
+    extends: api.app.local
+    exec: ./migrate
+    environment:
+      MODE: migrate
+  api.worker.local:
+    extends:
+      container: worker
+      file: base.yml
+    environment:
+      QUEUE: jobs
+`,
+		"required.yml": `
+containers:
+  db.local:
+    image: postgres:10
+  api.app.local:
+    extends:
+      container: api
+      file: base.yml
+    exec: ./serve
+    depends_on:
+      - db.local
+`,
+		"base.yml": `
+containers:
+  api:
+    image: api:1
+    environment:
+      LEVEL: info
+  worker:
+    extends: api
+    exec: ./work
+`,
+	}
+	config, problems := loadTestConfig(t, files, nil, nil)
+	assertProblems(t, problems)
+
+	tests := []struct {
+		name        string
+		image       string
+		exec        string
+		environment map[string]string
+		dependsOn   []string
+	}{
+		{
+			name:        "api.app.local",
+			image:       "api:1",
+			exec:        "./serve",
+			environment: map[string]string{"LEVEL": "info"},
+			dependsOn:   []string{"db.local"},
+		},
+		{
+			name:        "api.migrate.tmp",
+			image:       "api:1",
+			exec:        "./migrate",
+			environment: map[string]string{"LEVEL": "info", "MODE": "migrate"},
+			dependsOn:   []string{"db.local"},
+		},
+		{
+			name:        "api.worker.local",
+			image:       "api:1",
+			exec:        "./work",
+			environment: map[string]string{"LEVEL": "info", "QUEUE": "jobs"},
+		},
+	}
+	for _, test := range tests {
+		ourContainer := config.Containers[test.name]
+		if ourContainer == nil {
+			t.Errorf("%s is missing", test.name)
+			continue
+		}
+		if ourContainer.Image != test.image || ourContainer.Exec != test.exec ||
+			!reflect.DeepEqual(ourContainer.Environment, test.environment) {
+			t.Errorf("%s has image %s, exec %v and environment %v", test.name, ourContainer.Image, ourContainer.Exec,
+				ourContainer.Environment)
+		}
+		if !reflect.DeepEqual(ourContainer.DependsStrings, test.dependsOn) {
+			t.Errorf("%s depends on %v, expected %v", test.name, ourContainer.DependsStrings, test.dependsOn)
+		}
+	}
+	// containers that are only in a file given to extends are not run
+	if _, ok := config.Containers["worker"]; ok {
+		t.Error("containers from a file given to extends were added to the config")
+	}
+}
+
+func TestExtendsProblems(t *testing.T) {
+	_, problems := loadTestConfig(t, map[string]string{
+		"constellation.yml": `
+containers:
+  loop.a:
+    extends: loop.b
+  loop.b:
+    extends: loop.a
+  missing.local:
+    extends: nope
+  elsewhere.local:
+    extends:
+      container: nope
+      file: base.yml
+  imageless.local:
+    extends:
+      container: imageless
+      file: base.yml
+`,
+		"base.yml": `
+containers:
+  imageless:
+    exec: ./serve
+`,
+	}, nil, nil)
+	assertProblems(t, problems,
+		"constellation.yml:4: extends cycle: loop.a -> loop.b -> loop.a",
+		"constellation.yml:6: extends cycle: loop.b -> loop.a -> loop.b",
+		"constellation.yml:8: container missing.local extends nope which does not exist in the config",
+		"constellation.yml:10: container elsewhere.local extends nope which does not exist in base.yml",
+		"constellation.yml:13: container imageless.local has no image")
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// keyLine matches a line (after any list markers) that starts a key in a yaml mapping.  The key is in the first group
+// and the value (if any) in the second
+var keyLine = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s"'#][^:#]*?)\s*:(?:\s+(.*))?$`)
+
+// lineIndex maps the path of each key and list item in a yaml document (e.g. containers/db.local/depends_on/0) to the
+// line it is on.  It only understands block style yaml (which is what our config files are written in); anything inside
+// a flow style value ({...} or [...]) is attributed to the key that holds it.
+type lineIndex map[string]int
+
+// indexLines will build a lineIndex for a yaml document
+func indexLines(data []byte) lineIndex {
+	index := make(lineIndex)
+
+	// frames track the keys and list items that contain the line we are on
+	type frame struct {
+		indent int
+		path   string
+		item   bool
+	}
+	frames := make([]frame, 0)
+	// items counts the list items we have seen under each path
+	items := make(map[string]int)
+	// once we see a block scalar (| or >) we skip everything indented further than its key
+	scalarIndent := -1
+
+	for number, line := range strings.Split(string(data), "\n") {
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		content = strings.TrimRight(content, " \t\r")
+		if content == "" || strings.HasPrefix(content, "#") || content == "---" {
+			continue
+		}
+		if scalarIndent >= 0 {
+			if indent > scalarIndent {
+				continue
+			}
+			scalarIndent = -1
+		}
+
+		// list items
+		for content == "-" || strings.HasPrefix(content, "- ") {
+			for len(frames) > 0 {
+				top := frames[len(frames)-1]
+				if top.indent > indent || (top.indent == indent && top.item) {
+					frames = frames[:len(frames)-1]
+				} else {
+					break
+				}
+			}
+			parent := ""
+			if len(frames) > 0 {
+				parent = frames[len(frames)-1].path
+			}
+			path := joinPath(parent, strconv.Itoa(items[parent]))
+			items[parent]++
+			index[path] = number + 1
+			frames = append(frames, frame{indent: indent, path: path, item: true})
+
+			// whatever follows the marker is indented as far as it starts
+			rest := strings.TrimLeft(content[1:], " ")
+			indent += len(content) - len(rest)
+			content = rest
+		}
+
+		// keys
+		match := keyLine.FindStringSubmatch(content)
+		if match == nil {
+			continue
+		}
+		for len(frames) > 0 && frames[len(frames)-1].indent >= indent {
+			frames = frames[:len(frames)-1]
+		}
+		parent := ""
+		if len(frames) > 0 {
+			parent = frames[len(frames)-1].path
+		}
+		path := joinPath(parent, strings.Trim(match[1], `"'`))
+		index[path] = number + 1
+		frames = append(frames, frame{indent: indent, path: path})
+
+		if strings.HasPrefix(match[2], "|") || strings.HasPrefix(match[2], ">") {
+			scalarIndent = indent
+		}
+	}
+	return index
+}
+
+// lookup returns the line of path, or of the closest thing that contains it.  0 is returned if we know nothing about it
+func (index lineIndex) lookup(path string) int {
+	for path != "" {
+		if line, ok := index[path]; ok {
+			return line
+		}
+		if cut := strings.LastIndex(path, "/"); cut >= 0 {
+			path = path[:cut]
+		} else {
+			path = ""
+		}
+	}
+	return 0
+}
+
+// lookupBelow is lookup, but only for path and the things that contain it which are at least depth levels down
+func (index lineIndex) lookupBelow(path string, depth int) int {
+	for strings.Count(path, "/") >= depth {
+		if line, ok := index[path]; ok {
+			return line
+		}
+		path = path[:strings.LastIndex(path, "/")]
+	}
+	return 0
+}
+
+// joinPath adds key onto the end of path
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "/" + key
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndexLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		expected lineIndex
+	}{
+		{
+			name: "nested keys",
+			yaml: `containers:
+  db.local:
+    image: postgres
+    environment:
+      USER: app`,
+			expected: lineIndex{
+				"containers":                           1,
+				"containers/db.local":                  2,
+				"containers/db.local/image":            3,
+				"containers/db.local/environment":      4,
+				"containers/db.local/environment/USER": 5,
+			},
+		},
+		{
+			name: "lists",
+			yaml: `depends_on:
+  - db.local
+  - cache.local
+mounts:
+- volume: data
+  path: /data
+- volume: logs
+  path: /logs`,
+			expected: lineIndex{
+				"depends_on":      1,
+				"depends_on/0":    2,
+				"depends_on/1":    3,
+				"mounts":          4,
+				"mounts/0":        5,
+				"mounts/0/volume": 5,
+				"mounts/0/path":   6,
+				"mounts/1":        7,
+				"mounts/1/volume": 7,
+				"mounts/1/path":   8,
+			},
+		},
+		{
+			name: "lists of lists",
+			yaml: `all:
+  - output:
+      - source: STDOUT
+        regex: ready
+  - - nested`,
+			expected: lineIndex{
+				"all":                   1,
+				"all/0":                 2,
+				"all/0/output":          2,
+				"all/0/output/0":        3,
+				"all/0/output/0/source": 3,
+				"all/0/output/0/regex":  4,
+				"all/1":                 5,
+				"all/1/0":               5,
+			},
+		},
+		{
+			name: "comments, blank lines and quoted keys",
+			yaml: `# a comment
+---
+"quoted": 1
+
+'single': 2
+other: 3 # trailing`,
+			expected: lineIndex{"quoted": 3, "single": 5, "other": 6},
+		},
+		{
+			name: "block and flow scalars",
+			yaml: `exec: |
+  not: a key
+  - nor an item
+codes: [0, 1]
+environment: {A: b}
+after: 1`,
+			expected: lineIndex{"exec": 1, "codes": 4, "environment": 5, "after": 6},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			index := indexLines([]byte(test.yaml))
+			if !reflect.DeepEqual(index, test.expected) {
+				t.Errorf("indexed %v, expected %v", index, test.expected)
+			}
+		})
+	}
+}
+
+func TestLineIndexLookup(t *testing.T) {
+	index := lineIndex{
+		"containers":                       1,
+		"containers/db.local":              2,
+		"containers/db.local/depends_on":   3,
+		"containers/db.local/depends_on/0": 4,
+		"containers/api.local":             5,
+		"containers/api.local/environment": 6,
+	}
+	tests := []struct {
+		path     string
+		depth    int
+		expected int
+		below    int
+	}{
+		{path: "containers/db.local/depends_on/0", depth: 2, expected: 4, below: 4},
+		{path: "containers/db.local/depends_on/1", depth: 2, expected: 3, below: 3},
+		{path: "containers/api.local/image", depth: 1, expected: 5, below: 5},
+		{path: "containers/api.local/image", depth: 2, expected: 5, below: 0},
+		{path: "containers/cache.local/image", depth: 2, expected: 1, below: 0},
+		{path: "volumes/data", depth: 1, expected: 0, below: 0},
+	}
+
+	for _, test := range tests {
+		if line := index.lookup(test.path); line != test.expected {
+			t.Errorf("looked up %s on line %d, expected %d", test.path, line, test.expected)
+		}
+		if line := index.lookupBelow(test.path, test.depth); line != test.below {
+			t.Errorf("looked up %s below depth %d on line %d, expected %d", test.path, test.depth, line, test.below)
+		}
+	}
+}
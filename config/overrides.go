@@ -0,0 +1,235 @@
+package config
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dansteen/constellation/container"
+	"github.com/dansteen/constellation/types"
+	"github.com/ghodss/yaml"
+)
+
+// override is a file that patches the containers and volumes of a config
+type override struct {
+	src source
+	raw map[string]interface{}
+}
+
+// loadOverride will read an override file and check its keys.  Variables are substituted in when it is applied, since
+// they can come from override files that are applied after it.  We also return whether the file could be read.
+func loadOverride(fileName string, includeDirs []string) (override, Problems, bool) {
+	layer := override{raw: make(map[string]interface{})}
+	filePath, err := findFile(fileName, includeDirs)
+	if err != nil {
+		return layer, Problems{{File: fileName, Message: err.Error()}}, false
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return layer, Problems{{File: filePath, Message: err.Error()}}, false
+	}
+	layer.src = source{file: filePath, lines: indexLines(data)}
+
+	var raw interface{}
+	err = yaml.Unmarshal(data, &raw)
+	if err != nil {
+		problem := Problem{File: filePath, Message: err.Error()}
+		if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			problem.Line, _ = strconv.Atoi(match[1])
+		}
+		return layer, Problems{problem}, false
+	}
+	if raw != nil {
+		var ok bool
+		layer.raw, ok = raw.(map[string]interface{})
+		if !ok {
+			return layer, Problems{{File: filePath, Message: "override files must be a hash"}}, false
+		}
+	}
+
+	problems := checkPatchKeys(layer.src, layer.raw, reflect.TypeOf(Config{}), "")
+	if _, ok := layer.raw["require"]; ok {
+		problems = append(problems, layer.src.problem("require", "require can not be used in override files"))
+	}
+	return layer, problems, true
+}
+
+// applyOverride will patch the containers and volumes in layer onto our config
+func (config *Config) applyOverride(layer override, variables map[string]string) Problems {
+	raw, problems := interpolate(layer.src, layer.raw, variables, "")
+	patches, _ := raw.(map[string]interface{})
+
+	containers, _ := patches["containers"].(map[string]interface{})
+	for _, name := range sortedKeys(containers) {
+		path := joinPath("containers", name)
+		if containers[name] == nil {
+			delete(config.Containers, name)
+			delete(config.containerSources, name)
+			delete(config.containerDefinitions, name)
+			continue
+		}
+		layer.src.lines.indexPatch(config.containerDefinitions[name], containers[name], path)
+		definition, _ := mergePatch(config.containerDefinitions[name], containers[name]).(map[string]interface{})
+		patched := &container.Container{}
+		definition, decodeProblems, decoded := decodeDefinition(layer.src, definition, patched, path)
+		problems = append(problems, decodeProblems...)
+		if !decoded {
+			continue
+		}
+		patched.Name = name
+		if _, ok := config.Containers[name]; ok {
+			src := config.containerSources[name]
+			src.patches = append(append([]source{}, src.patches...), layer.src)
+			config.containerSources[name] = src
+		} else {
+			config.containerSources[name] = layer.src
+		}
+		config.Containers[name] = patched
+		config.containerDefinitions[name] = definition
+	}
+
+	volumes, _ := patches["volumes"].(map[string]interface{})
+	for _, name := range sortedKeys(volumes) {
+		path := joinPath("volumes", name)
+		if volumes[name] == nil {
+			delete(config.Volumes, name)
+			delete(config.volumeSources, name)
+			delete(config.volumeDefinitions, name)
+			continue
+		}
+		layer.src.lines.indexPatch(config.volumeDefinitions[name], volumes[name], path)
+		definition, _ := mergePatch(config.volumeDefinitions[name], volumes[name]).(map[string]interface{})
+		patched := types.Volume{}
+		definition, decodeProblems, decoded := decodeDefinition(layer.src, definition, &patched, path)
+		problems = append(problems, decodeProblems...)
+		if !decoded {
+			continue
+		}
+		patched.Name = name
+		if _, ok := config.Volumes[name]; ok {
+			src := config.volumeSources[name]
+			src.patches = append(append([]source{}, src.patches...), layer.src)
+			config.volumeSources[name] = src
+		} else {
+			config.volumeSources[name] = layer.src
+		}
+		config.Volumes[name] = patched
+		config.volumeDefinitions[name] = definition
+	}
+	return problems
+}
+
+// mergePatch applies patch to base the way a JSON merge patch (RFC 7386) does: hashes are merged key by key, null
+// removes a key and anything else replaces what was there.  On top of that, a key ending in + adds its list onto the end
+// of the list it names rather than replacing it.
+func mergePatch(base interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	merged := make(map[string]interface{})
+	if baseMap, ok := base.(map[string]interface{}); ok {
+		for key, value := range baseMap {
+			merged[key] = value
+		}
+	}
+	for key, value := range patchMap {
+		if strings.HasSuffix(key, "+") {
+			key = strings.TrimSuffix(key, "+")
+			merged[key] = mergeLists(merged[key], value, nil)
+		} else if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = mergePatch(merged[key], value)
+		}
+	}
+	return merged
+}
+
+// indexPatch adds the lines of anything that patch (which is at path) adds to a list with a key+ to our index, under the
+// path it ends up at once patch is merged onto base.  That way problems with those items can be found in the override
+// file.
+func (index lineIndex) indexPatch(base interface{}, patch interface{}, path string) {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return
+	}
+	baseMap, _ := base.(map[string]interface{})
+	for key, value := range patchMap {
+		if !strings.HasSuffix(key, "+") {
+			index.indexPatch(baseMap[key], value, joinPath(path, key))
+			continue
+		}
+		// the items we add go after whatever was already there
+		listKey := strings.TrimSuffix(key, "+")
+		offset := 0
+		if baseList, ok := baseMap[listKey].([]interface{}); ok {
+			offset = len(baseList)
+		}
+		patchPath := joinPath(path, key) + "/"
+		listPath := joinPath(path, listKey) + "/"
+		for itemPath, line := range index {
+			if !strings.HasPrefix(itemPath, patchPath) {
+				continue
+			}
+			rest := strings.SplitN(strings.TrimPrefix(itemPath, patchPath), "/", 2)
+			item, err := strconv.Atoi(rest[0])
+			if err != nil {
+				continue
+			}
+			rest[0] = strconv.Itoa(item + offset)
+			index[listPath+strings.Join(rest, "/")] = line
+		}
+		if _, ok := index[joinPath(path, listKey)]; !ok {
+			index[joinPath(path, listKey)] = index[joinPath(path, key)]
+		}
+	}
+}
+
+// decodeDefinition is decode for the raw definition of a container or volume
+func decodeDefinition(src source, definition map[string]interface{}, target interface{}, path string) (map[string]interface{}, Problems, bool) {
+	value, problems, decoded := decode(src, definition, target, path)
+	definition, _ = value.(map[string]interface{})
+	return definition, problems, decoded
+}
+
+// checkPatchKeys is checkKeys for override files, which can also use null to remove a key and key+ to add to a list.
+// key+ on something that isn't a list is removed from value once it is reported, so that it is not reported again when
+// value is applied
+func checkPatchKeys(src source, value interface{}, valueType reflect.Type, path string) Problems {
+	problems := make(Problems, 0)
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	typed, ok := value.(map[string]interface{})
+	if !ok {
+		// lists replace whatever was there so are checked as they are
+		return checkKeys(src, value, valueType, path)
+	}
+	switch valueType.Kind() {
+	case reflect.Map:
+		for _, key := range sortedKeys(typed) {
+			problems = append(problems, checkPatchKeys(src, typed[key], valueType.Elem(), joinPath(path, key))...)
+		}
+	case reflect.Struct:
+		fields := jsonFields(valueType)
+		for _, key := range sortedKeys(typed) {
+			name := strings.TrimSuffix(key, "+")
+			field, ok := fields[name]
+			if !ok {
+				problems = append(problems, src.problem(joinPath(path, key), "unknown key %s", key))
+				continue
+			}
+			if name != key && field.Type.Kind() != reflect.Slice {
+				problems = append(problems, src.problem(joinPath(path, key), "%s can only be used with lists", key))
+				delete(typed, key)
+				continue
+			}
+			problems = append(problems, checkPatchKeys(src, typed[key], field.Type, joinPath(path, key))...)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "hashes are merged",
+			base:     "{image: api:1, environment: {LEVEL: info, MODE: serve}}",
+			patch:    "{environment: {LEVEL: debug}}",
+			expected: "{image: api:1, environment: {LEVEL: debug, MODE: serve}}",
+		},
+		{
+			name:     "null removes a key",
+			base:     "{image: api:1, state_conditions: {timeout: {duration: 5, status: failure}, exit: {codes: [0], status: success}}}",
+			patch:    "{state_conditions: {timeout: null}}",
+			expected: "{image: api:1, state_conditions: {exit: {codes: [0], status: success}}}",
+		},
+		{
+			name:     "lists are replaced",
+			base:     "{depends_on: [db.local, cache.local]}",
+			patch:    "{depends_on: [queue.local]}",
+			expected: "{depends_on: [queue.local]}",
+		},
+		{
+			name:     "+ adds to a list",
+			base:     "{depends_on: [db.local], mounts: [{volume: data, path: /data}]}",
+			patch:    "{depends_on+: [queue.local], mounts+: [{volume: data, path: /data}]}",
+			expected: "{depends_on: [db.local, queue.local], mounts: [{volume: data, path: /data}, {volume: data, path: /data}]}",
+		},
+		{
+			name:     "+ in a nested hash",
+			base:     "{state_conditions: {output: [{source: STDOUT, regex: ready, status: success}]}}",
+			patch:    "{state_conditions: {output+: [{source: STDERR, regex: panic, status: failure}]}}",
+			expected: "{state_conditions: {output: [{source: STDOUT, regex: ready, status: success}, {source: STDERR, regex: panic, status: failure}]}}",
+		},
+		{
+			name:     "+ without a list to add to",
+			base:     "{image: api:1}",
+			patch:    "{depends_on+: [db.local]}",
+			expected: "{image: api:1, depends_on: [db.local]}",
+		},
+		{
+			name:     "new keys",
+			base:     "{}",
+			patch:    "{image: api:1, environment: {LEVEL: info}}",
+			expected: "{image: api:1, environment: {LEVEL: info}}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertValue(t, mergePatch(yamlValue(t, test.base), yamlValue(t, test.patch)), test.expected)
+		})
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	files := map[string]string{
+		"constellation.yml": `
+variables:
+  TAG: "1"
+volumes:
+  data:
+    kind: empty
+containers:
+  db.local:
+    image: postgres:10
+    environment:
+      POSTGRES_USER: app
+      POSTGRES_PASSWORD: secret
+  api.migrate.tmp:
+    image: api:${TAG}
+    depends_on: [db.local]
+  api.app.local:
+    image: api:${TAG}
+    depends_on: [db.local, api.migrate.tmp]
+`,
+		"local.yml": `
+variables:
+  TAG: "2"
+volumes:
+  scratch:
+    kind: empty
+containers:
+  db.local:
+    environment:
+      POSTGRES_PASSWORD: null
+      POSTGRES_HOST_AUTH_METHOD: trust
+    mounts+:
+      - volume: scratch
+        path: /scratch
+  api.migrate.tmp: null
+  api.app.local:
+    depends_on: [db.local]
+  mail.local:
+    image: mailhog:1
+`,
+		"later.yml": `
+variables:
+  TAG: "3"
+containers:
+  api.app.local:
+    depends_on+: [mail.local]
+`,
+	}
+	config, problems := loadTestConfig(t, files, []string{"local.yml", "later.yml"}, nil)
+	assertProblems(t, problems)
+
+	if names := sortedNames(config.Containers); !reflect.DeepEqual(names, []string{"api.app.local", "db.local", "mail.local"}) {
+		t.Errorf("containers are %v", names)
+	}
+	if names := sortedNames(config.Volumes); !reflect.DeepEqual(names, []string{"data", "scratch"}) {
+		t.Errorf("volumes are %v", names)
+	}
+	db := config.Containers["db.local"]
+	if !reflect.DeepEqual(db.Environment, map[string]string{"POSTGRES_USER": "app", "POSTGRES_HOST_AUTH_METHOD": "trust"}) {
+		t.Errorf("db.local has environment %v", db.Environment)
+	}
+	if len(db.Mounts) != 1 || db.Mounts[0].Volume != "scratch" {
+		t.Errorf("db.local has mounts %+v", db.Mounts)
+	}
+	app := config.Containers["api.app.local"]
+	if app.Image != "api:3" {
+		t.Errorf("api.app.local has image %s, expected the variable from the last override file", app.Image)
+	}
+	if !reflect.DeepEqual(app.DependsStrings, []string{"db.local", "mail.local"}) {
+		t.Errorf("api.app.local depends on %v", app.DependsStrings)
+	}
+}
+
+func TestOverrideProblems(t *testing.T) {
+	files := map[string]string{
+		"constellation.yml": `
+containers:
+  db.local:
+    image: postgres:10
+  api.app.local:
+    image: api:1
+    depends_on:
+      - db.local
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready
+          status: success
+`,
+		"local.yml": `
+require:
+  - other.yml
+containers:
+  api.app.local:
+    image+: [api]
+    bogus: 1
+    depends_on+:
+      - cache.local
+    mounts:
+      - volume: missing
+        path: /data
+    state_conditions:
+      output+:
+        - source: STDERR
+          regex: yes
+          status: failure
+`,
+	}
+	_, problems := loadTestConfig(t, files, []string{"local.yml"}, nil)
+	// problems with what an override file set are reported against it, including the items it adds with key+
+	assertProblems(t, problems,
+		"local.yml:2: require can not be used in override files",
+		"local.yml:6: image+ can only be used with lists",
+		"local.yml:7: unknown key bogus",
+		"local.yml:9: container api.app.local depends on cache.local which does not exist in the config",
+		"local.yml:11: mount in api.app.local references volume missing which is not defined",
+		"local.yml:16: container api.app.local: regex must be a string, not true or false.  Quote it if it is meant to be a string")
+}
@@ -6,37 +6,153 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
+	"regexp"
+	"strconv"
 
 	"github.com/dansteen/constellation/util"
 	"github.com/ghodss/yaml"
 )
 
 // ProcessFile will process config files for constellation, and return an array of Config objects
-// required files are processed as well.  Accepts a filepath and an array of directories to search for
-// files that are included via the 'require' stanza (or the file provided)
-func ProcessFile(fileName string, includeDirs []string) Config {
-
-	// first make sure the file exists
-	filePath, err := findFile(fileName, includeDirs)
+// required files are processed as well.  Accepts a filepath, override files to apply on top of it, an array of
+// directories to search for files that are included via the 'require' stanza (or the files provided), and variables to
+// substitute into the config on top of those in the environment and its variables stanzas.  Any problems with the
+// config are fatal.
+func ProcessFile(fileName string, overrides []string, includeDirs []string, variables map[string]string) Config {
+	config, err := LoadFile(fileName, overrides, includeDirs, variables)
 	util.Check(err)
+	return config
+}
+
+// LoadFile will load a config file along with any files it requires, apply each override file to it in order, and check
+// the result for problems.  If there are any, they are returned as a Problems error.
+func LoadFile(fileName string, overrides []string, includeDirs []string, variables map[string]string) (Config, error) {
+	problems := make(Problems, 0)
+	readable := true
+
+	// read our override files first, since their variables are used by everything underneath them
+	layers := make([]override, 0)
+	layerVariables := make(map[string]string)
+	for _, overrideFile := range overrides {
+		layer, layerProblems, layerReadable := loadOverride(overrideFile, includeDirs)
+		problems = append(problems, layerProblems...)
+		readable = readable && layerReadable
+		ourVariables, variableProblems := fileVariables(layer.src, layer.raw)
+		problems = append(problems, variableProblems...)
+		for name, value := range ourVariables {
+			layerVariables[name] = value
+		}
+		layers = append(layers, layer)
+	}
+	for name, value := range variables {
+		layerVariables[name] = value
+	}
+
+	config, fileProblems, fileReadable := loadFile(fileName, includeDirs, layerVariables)
+	problems = append(problems, fileProblems...)
+	readable = readable && fileReadable
+
+	// the files we just loaded can set variables that our override files use too
+	allVariables := make(map[string]string)
+	for name, value := range config.Variables {
+		allVariables[name] = value
+	}
+	for name, value := range layerVariables {
+		allVariables[name] = value
+	}
+	for _, layer := range layers {
+		problems = append(problems, config.applyOverride(layer, allVariables)...)
+	}
+
+	// there is no point looking for problems in a config we could not read properly
+	if readable {
+		problems = append(problems, config.resolveExtends(includeDirs, layerVariables)...)
+		problems = append(problems, config.Validate()...)
+	}
+	if len(problems) > 0 {
+		problems.sort()
+		return config, problems
+	}
+	return config, nil
+}
 
+// yamlErrorLine pulls the line number out of yaml parse errors
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// loadFile does the heavy lifting for LoadFile.  Each file is parsed strictly: keys that we don't know about are
+// reported as problems rather than ignored.  Variables are substituted in before the file is unmarshalled; those passed
+// in win over the ones in the file's variables stanza, which lets the file that requires another set them.  We also
+// return whether every file could be read.
+func loadFile(fileName string, includeDirs []string, variables map[string]string) (Config, Problems, bool) {
 	// setup a map to hold our config
 	config := Config{}
+	config.init()
+
+	// first make sure the file exists
+	filePath, err := findFile(fileName, includeDirs)
+	if err != nil {
+		return config, Problems{{File: fileName, Message: err.Error()}}, false
+	}
 
 	// read in the file provided
 	data, err := ioutil.ReadFile(filePath)
-	util.Check(err)
-	err = yaml.Unmarshal(data, &config)
-	//util.Check(err)
-	fmt.Printf("%+v\n", err)
+	if err != nil {
+		return config, Problems{{File: filePath, Message: err.Error()}}, false
+	}
+	src := source{file: filePath, lines: indexLines(data)}
+	var raw interface{}
+	err = yaml.Unmarshal(data, &raw)
+	if err != nil {
+		problem := Problem{File: filePath, Message: err.Error()}
+		if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			problem.Line, _ = strconv.Atoi(match[1])
+		}
+		return config, Problems{problem}, false
+	}
+
+	// substitute in our variables
+	ourVariables, problems := fileVariables(src, raw)
+	for name, value := range variables {
+		ourVariables[name] = value
+	}
+	raw, variableProblems := interpolate(src, raw, ourVariables, "")
+	problems = append(problems, variableProblems...)
+
+	// look for any keys that we would otherwise silently ignore
+	problems = append(problems, checkKeys(src, raw, reflect.TypeOf(config), "")...)
+
+	// and then unmarshal the result.  anything that can't be unmarshalled is left out so we can check the rest
+	raw, decodeProblems, decoded := decode(src, raw, &config, "")
+	problems = append(problems, decodeProblems...)
+	config.init()
+	if !decoded {
+		return config, problems, false
+	}
+
+	// remember where everything came from, and what it looked like
+	top, _ := raw.(map[string]interface{})
+	containerDefinitions, _ := top["containers"].(map[string]interface{})
+	for name := range config.Containers {
+		config.containerSources[name] = src
+		config.containerDefinitions[name], _ = containerDefinitions[name].(map[string]interface{})
+	}
+	volumeDefinitions, _ := top["volumes"].(map[string]interface{})
+	for name := range config.Volumes {
+		config.volumeSources[name] = src
+		config.volumeDefinitions[name], _ = volumeDefinitions[name].(map[string]interface{})
+	}
 
 	// run through and merge any reqired files in
+	readable := true
 	for _, requirePath := range config.Requires {
 		// get containers from the requires and add them to our list
-		requireConfig := ProcessFile(requirePath, includeDirs)
+		requireConfig, requireProblems, requireReadable := loadFile(requirePath, includeDirs, ourVariables)
+		problems = append(problems, requireProblems...)
+		readable = readable && requireReadable
 		config = config.Merge(requireConfig)
 	}
-	return config
+	return config, problems, readable
 }
 
 // findFile will return the first combination of includeDirs and fileName that exists on the system
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Profiles returns the names of every profile used in the config
+func (config *Config) Profiles() []string {
+	seen := make(map[string]bool)
+	profiles := make([]string, 0)
+	for _, ourContainer := range config.Containers {
+		for _, profile := range ourContainer.Profiles {
+			if !seen[profile] {
+				seen[profile] = true
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// EnableProfiles will remove every container that has profiles from the config, unless one of its profiles is in
+// profiles.  Containers without profiles are always kept.
+func (config *Config) EnableProfiles(profiles []string) error {
+	known := config.Profiles()
+	for _, profile := range profiles {
+		if !contains(known, profile) {
+			return errors.New(fmt.Sprintf("Unknown profile %s.  The profiles in the config are %v", profile, known))
+		}
+	}
+	for name, ourContainer := range config.Containers {
+		if !profileEnabled(ourContainer.Profiles, profiles) {
+			delete(config.Containers, name)
+			delete(config.containerSources, name)
+			delete(config.containerDefinitions, name)
+		}
+	}
+	return nil
+}
+
+// profileEnabled checks if a container with containerProfiles is enabled when profiles are
+func profileEnabled(containerProfiles []string, profiles []string) bool {
+	if len(containerProfiles) == 0 {
+		return true
+	}
+	for _, profile := range containerProfiles {
+		if contains(profiles, profile) {
+			return true
+		}
+	}
+	return false
+}
+
+// profileDependencyOK checks if a container with containerProfiles can depend on one with depProfiles.  This is only the
+// case if the dependency is enabled every time the container is, which means it either has no profiles, or it has every
+// profile the container has.
+func profileDependencyOK(containerProfiles []string, depProfiles []string) bool {
+	if len(depProfiles) == 0 {
+		return true
+	}
+	if len(containerProfiles) == 0 {
+		return false
+	}
+	for _, profile := range containerProfiles {
+		if !contains(depProfiles, profile) {
+			return false
+		}
+	}
+	return true
+}
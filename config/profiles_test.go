@@ -0,0 +1,145 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProfileEnabled(t *testing.T) {
+	tests := []struct {
+		name              string
+		containerProfiles []string
+		profiles          []string
+		expected          bool
+	}{
+		{name: "no profiles", expected: true},
+		{name: "no profiles with profiles enabled", profiles: []string{"debug"}, expected: true},
+		{name: "not enabled", containerProfiles: []string{"debug"}, expected: false},
+		{name: "enabled", containerProfiles: []string{"debug"}, profiles: []string{"debug"}, expected: true},
+		{name: "one of several", containerProfiles: []string{"debug", "mail"}, profiles: []string{"mail"}, expected: true},
+		{name: "other profiles", containerProfiles: []string{"debug"}, profiles: []string{"mail"}, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := profileEnabled(test.containerProfiles, test.profiles); result != test.expected {
+				t.Errorf("profileEnabled(%v, %v) is %v", test.containerProfiles, test.profiles, result)
+			}
+		})
+	}
+}
+
+func TestProfileDependencyOK(t *testing.T) {
+	tests := []struct {
+		name              string
+		containerProfiles []string
+		depProfiles       []string
+		expected          bool
+	}{
+		{name: "neither has profiles", expected: true},
+		{name: "dependency has no profiles", containerProfiles: []string{"debug"}, expected: true},
+		{name: "only the dependency has profiles", depProfiles: []string{"debug"}, expected: false},
+		{name: "same profiles", containerProfiles: []string{"debug"}, depProfiles: []string{"debug"}, expected: true},
+		{name: "dependency has more", containerProfiles: []string{"debug"}, depProfiles: []string{"debug", "mail"}, expected: true},
+		{name: "dependency has fewer", containerProfiles: []string{"debug", "mail"}, depProfiles: []string{"debug"}, expected: false},
+		{name: "different profiles", containerProfiles: []string{"debug"}, depProfiles: []string{"mail"}, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := profileDependencyOK(test.containerProfiles, test.depProfiles); result != test.expected {
+				t.Errorf("profileDependencyOK(%v, %v) is %v", test.containerProfiles, test.depProfiles, result)
+			}
+		})
+	}
+}
+
+const profilesConfig = `
+containers:
+  db.local:
+    image: postgres:10
+  api.app.local:
+    image: api:1
+    depends_on: [db.local]
+  mail.local:
+    image: mailhog:1
+    profiles: [mail]
+  debug.local:
+    image: debug:1
+    profiles: [debug, mail]
+    depends_on: [db.local]
+`
+
+func TestEnableProfiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		profiles   []string
+		containers []string
+		err        string
+	}{
+		{
+			name:       "none",
+			containers: []string{"api.app.local", "db.local"},
+		},
+		{
+			name:       "one",
+			profiles:   []string{"debug"},
+			containers: []string{"api.app.local", "db.local", "debug.local"},
+		},
+		{
+			name:       "several",
+			profiles:   []string{"debug", "mail"},
+			containers: []string{"api.app.local", "db.local", "debug.local", "mail.local"},
+		},
+		{
+			name:     "unknown",
+			profiles: []string{"mial"},
+			err:      "Unknown profile mial.  The profiles in the config are [debug mail]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, problems := loadTestConfig(t, map[string]string{"constellation.yml": profilesConfig}, nil, nil)
+			assertProblems(t, problems)
+			if profiles := config.Profiles(); !reflect.DeepEqual(profiles, []string{"debug", "mail"}) {
+				t.Errorf("config has profiles %v", profiles)
+			}
+
+			err := config.EnableProfiles(test.profiles)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := sortedNames(config.Containers); !reflect.DeepEqual(names, test.containers) {
+				t.Errorf("containers are %v, expected %v", names, test.containers)
+			}
+		})
+	}
+}
+
+func TestProfileProblems(t *testing.T) {
+	_, problems := loadTestConfig(t, map[string]string{"constellation.yml": `
+containers:
+  mail.local:
+    image: mailhog:1
+    profiles: [mail]
+  api.app.local:
+    image: api:1
+    depends_on:
+      - mail.local
+  debug.local:
+    image: debug:1
+    profiles: [debug]
+    depends_on:
+      - mail.local
+`}, nil, nil)
+	assertProblems(t, problems,
+		"constellation.yml:9: container api.app.local has no profiles but depends on mail.local which is only enabled by profiles [mail]",
+		"constellation.yml:14: container debug.local (profiles [debug]) depends on mail.local which is only enabled by profiles [mail]")
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dansteen/constellation/container"
+	"github.com/dansteen/constellation/state"
+)
+
+// schemaEnums are the values allowed for keys that only accept a fixed set of values, by key name
+var schemaEnums = map[string][]string{
+	"status": statuses,
+	"kind":   volumeKinds,
+	"action": container.LivenessActions,
+	"policy": container.RestartPolicies,
+	"source": outputSources,
+	"via":    state.Vias,
+}
+
+// schemaRequired are the keys that must be set, by the type they are set on
+var schemaRequired = map[string][]string{
+	"types.Mount":                {"volume", "path"},
+	"state.ExitCondition":        {"codes", "status"},
+	"state.TimeoutCondition":     {"duration", "status"},
+	"state.OutputCondition":      {"source", "status"},
+	"state.FileMonitorCondition": {"file", "status"},
+	"state.HTTPCondition":        {"port", "status"},
+	"state.TCPCondition":         {"port", "status"},
+	"state.CommandCondition":     {"command", "status"},
+}
+
+// schemaOverrides replace the schema of keys that are unmarshalled into something other than the type of their field,
+// by <type>.<key>
+var schemaOverrides = map[string]map[string]interface{}{
+	// commands are split up the same way exec is
+	"state.CommandCondition.command": {"type": "string"},
+	// extends can be just the name of a container
+	"container.Container.extends": {
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"container": map[string]interface{}{"type": "string"},
+					"file":      map[string]interface{}{"type": "string"},
+				},
+				"required": []string{"container"},
+			},
+		},
+	},
+}
+
+// schemaAlternatives are the sets of keys that a type must have one of, by the type they are set on
+var schemaAlternatives = map[string][]string{
+	// containers that extend another can get their image from it
+	"container.Container": {"image", "extends"},
+}
+
+// schemaChoices are the sets of keys that a type must have exactly one of, by the type they are set on
+var schemaChoices = map[string][]string{
+	// matchers look for either a regex or a sequence of them
+	"state.OutputCondition":      {"regex", "sequence"},
+	"state.FileMonitorCondition": {"regex", "sequence"},
+}
+
+// regexpType is handled specially since regexes are strings in our config files
+var regexpType = reflect.TypeOf(regexp.Regexp{})
+
+// Schema generates a JSON Schema (draft-07) describing constellation config files.  It is generated from the types that
+// config files are unmarshalled into, so it always matches what we accept.
+func Schema() map[string]interface{} {
+	definitions := make(map[string]interface{})
+	root := schemaFor(reflect.TypeOf(Config{}), definitions)
+	// the root is a definition like any other, but editors want it at the top
+	root = definitions[strings.TrimPrefix(root["$ref"].(string), "#/definitions/")].(map[string]interface{})
+	delete(definitions, "config.Config")
+
+	schema := map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "constellation config file",
+		"definitions": definitions,
+	}
+	for key, value := range root {
+		schema[key] = value
+	}
+	return schema
+}
+
+// schemaFor generates the schema of valueType.  Structs are added to definitions and referenced, which lets them nest
+// inside themselves (e.g. all, any and not blocks of state conditions)
+func schemaFor(valueType reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	if valueType == regexpType {
+		return map[string]interface{}{"type": "string", "format": "regex"}
+	}
+
+	switch valueType.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(valueType.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(valueType.Elem(), definitions)}
+	case reflect.Struct:
+		name := valueType.String()
+		ref := map[string]interface{}{"$ref": "#/definitions/" + name}
+		if _, ok := definitions[name]; ok {
+			return ref
+		}
+		// reserve our spot before we look at our fields in case they refer back to us
+		definition := map[string]interface{}{"type": "object", "additionalProperties": false}
+		definitions[name] = definition
+
+		properties := make(map[string]interface{})
+		for key, field := range jsonFields(valueType) {
+			property, ok := schemaOverrides[name+"."+key]
+			if !ok {
+				property = schemaFor(field.Type, definitions)
+			}
+			if values, ok := schemaEnums[key]; ok && property["type"] == "string" {
+				property["enum"] = values
+			}
+			properties[key] = property
+		}
+		definition["properties"] = properties
+		if required, ok := schemaRequired[name]; ok {
+			sorted := append([]string{}, required...)
+			sort.Strings(sorted)
+			definition["required"] = sorted
+		}
+		if keys, ok := schemaAlternatives[name]; ok {
+			definition["anyOf"] = schemaRequiredEach(keys)
+		}
+		if keys, ok := schemaChoices[name]; ok {
+			definition["oneOf"] = schemaRequiredEach(keys)
+		}
+		return ref
+	}
+	// anything else can be anything
+	return map[string]interface{}{}
+}
+
+// schemaRequiredEach generates a schema for each of keys that requires it
+func schemaRequiredEach(keys []string) []interface{} {
+	schemas := make([]interface{}, len(keys))
+	for index, key := range keys {
+		schemas[index] = map[string]interface{}{"required": []string{key}}
+	}
+	return schemas
+}
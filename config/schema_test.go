@@ -0,0 +1,103 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	// run our schema through json so that we compare it the way editors will see it
+	data, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     []string
+		expected string
+	}{
+		{
+			name:     "root",
+			path:     []string{"properties", "containers"},
+			expected: `{"type": "object", "additionalProperties": {"$ref": "#/definitions/container.Container"}}`,
+		},
+		{
+			name:     "unknown keys",
+			path:     []string{"definitions", "container.Container", "additionalProperties"},
+			expected: `false`,
+		},
+		{
+			name:     "image or extends",
+			path:     []string{"definitions", "container.Container", "anyOf"},
+			expected: `[{"required": ["image"]}, {"required": ["extends"]}]`,
+		},
+		{
+			name:     "extends",
+			path:     []string{"definitions", "container.Container", "properties", "extends", "oneOf", "0"},
+			expected: `{"type": "string"}`,
+		},
+		{
+			name:     "required",
+			path:     []string{"definitions", "state.HTTPCondition", "required"},
+			expected: `["port", "status"]`,
+		},
+		{
+			name:     "enum",
+			path:     []string{"definitions", "state.TCPCondition", "properties", "via"},
+			expected: `{"type": "string", "enum": ["pod", "host"]}`,
+		},
+		{
+			name:     "regex",
+			path:     []string{"definitions", "state.HTTPCondition", "properties", "body"},
+			expected: `{"type": "string", "format": "regex"}`,
+		},
+		{
+			name:     "output regex or sequence",
+			path:     []string{"definitions", "state.OutputCondition", "oneOf"},
+			expected: `[{"required": ["regex"]}, {"required": ["sequence"]}]`,
+		},
+		{
+			name:     "filemonitor regex or sequence",
+			path:     []string{"definitions", "state.FileMonitorCondition", "oneOf"},
+			expected: `[{"required": ["regex"]}, {"required": ["sequence"]}]`,
+		},
+		{
+			name:     "nested blocks",
+			path:     []string{"definitions", "state.StateConditions", "properties", "not"},
+			expected: `{"$ref": "#/definitions/state.StateConditions"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var value interface{} = schema
+			for _, key := range test.path {
+				switch typed := value.(type) {
+				case map[string]interface{}:
+					value = typed[key]
+				case []interface{}:
+					index, _ := strconv.Atoi(key)
+					value = typed[index]
+				default:
+					t.Fatalf("nothing at %v in the schema", test.path)
+				}
+			}
+			var expected interface{}
+			err := json.Unmarshal([]byte(test.expected), &expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(value, expected) {
+				t.Errorf("schema has %v at %v, expected %v", value, test.path, expected)
+			}
+		})
+	}
+}
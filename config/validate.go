@@ -0,0 +1,362 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dansteen/constellation/container"
+	"github.com/dansteen/constellation/state"
+	"github.com/twmb/algoimpl/go/graph"
+)
+
+// volumeKinds are the kinds of volume we know how to handle
+var volumeKinds = []string{"host", "empty"}
+
+// statuses are the values accepted by the status of a state condition
+var statuses = []string{"success", "failure"}
+
+// outputSources are the values accepted by the source of an output condition
+var outputSources = []string{"STDOUT", "STDERR"}
+
+// Problem is something wrong with a config file
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// String formats a problem the way compilers do, so editors can jump to it
+func (problem Problem) String() string {
+	if problem.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", problem.File, problem.Line, problem.Message)
+	}
+	return fmt.Sprintf("%s: %s", problem.File, problem.Message)
+}
+
+// Problems is a list of problems, which is also usable as an error
+type Problems []Problem
+
+// Error lists each of our problems on its own line
+func (problems Problems) Error() string {
+	lines := make([]string, len(problems))
+	for index, problem := range problems {
+		lines[index] = problem.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sort will put our problems in file and line order
+func (problems Problems) sort() {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+}
+
+// source records where something in our config was defined, so problems with it can be reported against the right file
+type source struct {
+	file  string
+	lines lineIndex
+	// patches are the override files that have changed what was defined here, in the order they were applied
+	patches []source
+}
+
+// problem creates a problem at path in this source.  Problems with something that an override file set are reported
+// against that file instead.
+func (src source) problem(path string, format string, args ...interface{}) Problem {
+	for index := len(src.patches) - 1; index >= 0; index-- {
+		patch := src.patches[index]
+		// everything in an override file is inside the container or volume it patches, so that is as far up as we look
+		if line := patch.lines.lookupBelow(path, 2); line > 0 {
+			return Problem{File: patch.file, Line: line, Message: fmt.Sprintf(format, args...)}
+		}
+	}
+	return Problem{File: src.file, Line: src.lines.lookup(path), Message: fmt.Sprintf(format, args...)}
+}
+
+// checkKeys will walk through the raw (unmarshalled into interface{}) value of a config file and report any keys that
+// do not match a field of the type they will be unmarshalled into.
+func checkKeys(src source, value interface{}, valueType reflect.Type, path string) Problems {
+	problems := make(Problems, 0)
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		switch valueType.Kind() {
+		case reflect.Map:
+			for _, key := range sortedKeys(typed) {
+				problems = append(problems, checkKeys(src, typed[key], valueType.Elem(), joinPath(path, key))...)
+			}
+		case reflect.Struct:
+			fields := jsonFields(valueType)
+			for _, key := range sortedKeys(typed) {
+				field, ok := fields[key]
+				if !ok {
+					problems = append(problems, src.problem(joinPath(path, key), "unknown key %s", key))
+					continue
+				}
+				problems = append(problems, checkKeys(src, typed[key], field.Type, joinPath(path, key))...)
+			}
+		}
+	case []interface{}:
+		if valueType.Kind() == reflect.Slice {
+			for index, item := range typed {
+				problems = append(problems, checkKeys(src, item, valueType.Elem(), joinPath(path, strconv.Itoa(index)))...)
+			}
+		}
+	}
+	return problems
+}
+
+// jsonFields returns the fields of a struct type that can be set from a config file, by the key that sets them.  Fields
+// of embedded structs are included, just like encoding/json does.
+func jsonFields(structType reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			for key, embedded := range jsonFields(field.Type) {
+				fields[key] = embedded
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// sortedKeys returns the keys of a map in order, so that problems are always reported in the same order
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate will check the config for anything that would stop it from running.  This does not need a runtime, so it
+// can't check things like whether images exist.
+func (config *Config) Validate() Problems {
+	problems := make(Problems, 0)
+
+	for _, name := range sortedNames(config.Volumes) {
+		volume := config.Volumes[name]
+		src := config.volumeSources[name]
+		path := joinPath("volumes", name)
+		if !contains(volumeKinds, volume.Kind) {
+			problems = append(problems, src.problem(joinPath(path, "kind"), "volume %s has kind %q.  Must be one of %v", name, volume.Kind, volumeKinds))
+		}
+	}
+
+	for _, name := range sortedNames(config.Containers) {
+		ourContainer := config.Containers[name]
+		src := config.containerSources[name]
+		path := joinPath("containers", name)
+
+		// containers that extend another have this checked once they are resolved, and an image that could not be
+		// decoded has already been reported
+		_, written := src.lines[joinPath(path, "image")]
+		_, decoded := config.containerDefinitions[name]["image"]
+		if ourContainer.Image == "" && ourContainer.Extends == nil && (decoded || !written) {
+			problems = append(problems, src.problem(path, "container %s has no image", name))
+		}
+
+		for index, dep := range ourContainer.DependsStrings {
+			depPath := joinPath(path, "depends_on/"+strconv.Itoa(index))
+			if depContainer, ok := config.Containers[dep]; !ok {
+				problems = append(problems, src.problem(depPath, "container %s depends on %s which does not exist in the config", name, dep))
+			} else if !profileDependencyOK(ourContainer.Profiles, depContainer.Profiles) {
+				// our dependency has to be enabled whenever we are
+				if len(ourContainer.Profiles) == 0 {
+					problems = append(problems, src.problem(depPath, "container %s has no profiles but depends on %s which is only enabled by profiles %v", name, dep, depContainer.Profiles))
+				} else {
+					problems = append(problems, src.problem(depPath, "container %s (profiles %v) depends on %s which is only enabled by profiles %v", name, ourContainer.Profiles, dep, depContainer.Profiles))
+				}
+			}
+		}
+
+		for index, mount := range ourContainer.Mounts {
+			if _, ok := config.Volumes[mount.Volume]; !ok {
+				problems = append(problems, src.problem(joinPath(path, "mounts/"+strconv.Itoa(index)), "mount in %s references volume %s which is not defined", name, mount.Volume))
+			}
+		}
+
+		// these fill in their defaults, so they are checked on copies to leave the config as it was written
+		if ourContainer.Restart != nil {
+			policy := *ourContainer.Restart
+			if err := policy.Validate(); err != nil {
+				restartPath := joinPath(path, "restart")
+				if !contains(container.RestartPolicies, policy.Policy) {
+					restartPath = joinPath(restartPath, "policy")
+				}
+				problems = append(problems, src.problem(restartPath, "container %s: %s", name, err))
+			}
+		}
+		if ourContainer.Liveness != nil {
+			liveness := *ourContainer.Liveness
+			if err := liveness.Validate(); err != nil {
+				problems = append(problems, src.problem(joinPath(path, "liveness/action"), "container %s: %s", name, err))
+			}
+		}
+
+		blocks := map[string]*state.StateConditions{"state_conditions": &ourContainer.StateConditions}
+		if ourContainer.Liveness != nil {
+			blocks["liveness"] = &ourContainer.Liveness.StateConditions
+		}
+		for key, block := range blocks {
+			problems = append(problems, config.checkConditions(src, name, block, joinPath(path, key))...)
+		}
+	}
+
+	// and finally make sure we can actually work out what order to start things in
+	for _, cycle := range config.cycles() {
+		src := config.containerSources[cycle[0]]
+		problems = append(problems, src.problem(joinPath("containers", cycle[0]+"/depends_on"), "dependency cycle between %s", strings.Join(cycle, ", ")))
+	}
+
+	problems.sort()
+	return problems
+}
+
+// checkConditions will check a block of state conditions (and any blocks nested in it) from the container name.  path
+// is where the block is in its config file.
+func (config *Config) checkConditions(src source, name string, conditions *state.StateConditions, path string) Problems {
+	problems := make(Problems, 0)
+	checkStatus := func(status string, conditionPath string) {
+		if !contains(statuses, status) {
+			problems = append(problems, src.problem(joinPath(conditionPath, "status"), "state condition in %s has status %q.  Must be one of %v", name, status, statuses))
+		}
+	}
+	checkVia := func(via string, conditionPath string) {
+		if !contains(state.Vias, via) {
+			problems = append(problems, src.problem(joinPath(conditionPath, "via"), "state condition in %s has via %q.  Must be one of %v", name, via, state.Vias))
+		}
+	}
+
+	if conditions.Exit != nil {
+		checkStatus(conditions.Exit.Status, joinPath(path, "exit"))
+	}
+	if conditions.Timeout != nil {
+		checkStatus(conditions.Timeout.Status, joinPath(path, "timeout"))
+	}
+	for index, condition := range conditions.Outputs {
+		conditionPath := joinPath(path, "output/"+strconv.Itoa(index))
+		checkStatus(condition.Status, conditionPath)
+		if !contains(outputSources, condition.Source) {
+			problems = append(problems, src.problem(joinPath(conditionPath, "source"), "output condition in %s has source %q.  Must be one of %v", name, condition.Source, outputSources))
+		}
+	}
+	for index, condition := range conditions.HTTP {
+		conditionPath := joinPath(path, "http/"+strconv.Itoa(index))
+		checkStatus(condition.Status, conditionPath)
+		checkVia(condition.Via, conditionPath)
+	}
+	for index, condition := range conditions.TCP {
+		conditionPath := joinPath(path, "tcp/"+strconv.Itoa(index))
+		checkStatus(condition.Status, conditionPath)
+		checkVia(condition.Via, conditionPath)
+	}
+	for index, condition := range conditions.Commands {
+		checkStatus(condition.Status, joinPath(path, "command/"+strconv.Itoa(index)))
+	}
+	for index, condition := range conditions.FileMonitors {
+		conditionPath := joinPath(path, "filemonitor/"+strconv.Itoa(index))
+		checkStatus(condition.Status, conditionPath)
+		// filemonitors run outside of the container so can only see files that are mounted
+		mounted := false
+		for _, mount := range config.Containers[name].Mounts {
+			if strings.HasPrefix(condition.File, mount.Path) {
+				mounted = true
+			}
+		}
+		if !mounted {
+			problems = append(problems, src.problem(joinPath(conditionPath, "file"), "file monitor in %s requests a path (%s) that is not prefixed by any mount path", name, condition.File))
+		}
+	}
+
+	for index := range conditions.All {
+		problems = append(problems, config.checkConditions(src, name, &conditions.All[index], joinPath(path, "all/"+strconv.Itoa(index)))...)
+	}
+	for index := range conditions.Any {
+		problems = append(problems, config.checkConditions(src, name, &conditions.Any[index], joinPath(path, "any/"+strconv.Itoa(index)))...)
+	}
+	if conditions.Not != nil {
+		problems = append(problems, config.checkConditions(src, name, conditions.Not, joinPath(path, "not"))...)
+	}
+	return problems
+}
+
+// cycles returns the names of the containers in each dependency cycle in our config
+func (config *Config) cycles() [][]string {
+	ourGraph := graph.New(graph.Directed)
+	nodes := make(map[string]graph.Node)
+	for _, name := range sortedNames(config.Containers) {
+		nodes[name] = ourGraph.MakeNode()
+		*nodes[name].Value = name
+	}
+
+	cycles := make([][]string, 0)
+	for _, name := range sortedNames(config.Containers) {
+		for _, dep := range config.Containers[name].DependsStrings {
+			if dep == name {
+				// containers that depend on themselves are cycles all on their own
+				cycles = append(cycles, []string{name})
+			}
+			if _, ok := nodes[dep]; ok {
+				ourGraph.MakeEdge(nodes[dep], nodes[name])
+			}
+		}
+	}
+
+	// any group of more than one container that can all reach each other is a cycle
+	for _, component := range ourGraph.StronglyConnectedComponents() {
+		if len(component) < 2 {
+			continue
+		}
+		cycle := make([]string, 0)
+		for _, node := range component {
+			cycle = append(cycle, (*node.Value).(string))
+		}
+		sort.Strings(cycle)
+		cycles = append(cycles, cycle)
+	}
+	return cycles
+}
+
+// sortedNames returns the keys of a map of containers or volumes in order
+func sortedNames(values interface{}) []string {
+	names := make([]string, 0)
+	for _, key := range reflect.ValueOf(values).MapKeys() {
+		names = append(names, key.String())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contains checks if value is in list
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
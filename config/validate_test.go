@@ -0,0 +1,229 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadTestConfig writes files into a directory of their own and loads constellation.yml from it with overrides applied
+// on top.  Problems are returned one per string, with file names relative to that directory.
+func loadTestConfig(t *testing.T, files map[string]string, overrides []string, variables map[string]string) (Config, []string) {
+	dir, err := ioutil.TempDir("", "constellation-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	overridePaths := make([]string, len(overrides))
+	for index, override := range overrides {
+		overridePaths[index] = filepath.Join(dir, override)
+	}
+
+	config, err := LoadFile(filepath.Join(dir, "constellation.yml"), overridePaths, []string{dir}, variables)
+	if err == nil {
+		return config, nil
+	}
+	problems, ok := err.(Problems)
+	if !ok {
+		t.Fatalf("expected Problems, got %v", err)
+	}
+	messages := make([]string, len(problems))
+	for index, problem := range problems {
+		messages[index] = strings.Replace(problem.String(), dir+string(filepath.Separator), "", -1)
+	}
+	return config, messages
+}
+
+// assertProblems fails the test if problems are not expected
+func assertProblems(t *testing.T, problems []string, expected ...string) {
+	t.Helper()
+	if len(expected) == 0 {
+		expected = nil
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("got problems:\n%s\nexpected:\n%s", strings.Join(problems, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name: "valid",
+			config: `
+volumes:
+  logs:
+    kind: host
+    path: /tmp/logs
+containers:
+  db.local:
+    image: postgres:10
+    mounts:
+      - volume: logs
+        path: /var/log/postgresql
+    state_conditions:
+      filemonitor:
+        - file: /var/log/postgresql/postgres.log
+          regex: ready
+          status: success
+  api.local:
+    image: api:1
+    depends_on: [db.local]
+    restart:
+      policy: on-failure
+    liveness:
+      http:
+        - port: http
+          via: host
+          status: failure
+      action: fail
+`,
+		},
+		{
+			name: "unknown keys",
+			config: `
+containers:
+  db.local:
+    imgae: postgres:10
+    image: postgres:10
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: ready
+          stauts: success
+`,
+			expected: []string{
+				"constellation.yml:4: unknown key imgae",
+				"constellation.yml:8: state condition in db.local has status \"\".  Must be one of [success failure]",
+				"constellation.yml:10: unknown key stauts",
+			},
+		},
+		{
+			name: "references",
+			config: `
+containers:
+  api.local:
+    image: api:1
+    depends_on:
+      - db.local
+    mounts:
+      - volume: data
+        path: /data
+    state_conditions:
+      filemonitor:
+        - file: /var/log/api.log
+          regex: ready
+          status: success
+`,
+			expected: []string{
+				"constellation.yml:6: container api.local depends on db.local which does not exist in the config",
+				"constellation.yml:8: mount in api.local references volume data which is not defined",
+				"constellation.yml:12: file monitor in api.local requests a path (/var/log/api.log) that is not prefixed by any mount path",
+			},
+		},
+		{
+			name: "values",
+			config: `
+volumes:
+  data:
+    kind: tmpfs
+containers:
+  api.local:
+    image: api:1
+    state_conditions:
+      output:
+        - source: STDIN
+          regex: ready
+          status: sucess
+      tcp:
+        - port: http
+          via: hots
+          status: success
+    restart:
+      policy: sometimes
+    liveness:
+      action: restart
+`,
+			expected: []string{
+				"constellation.yml:4: volume data has kind \"tmpfs\".  Must be one of [host empty]",
+				"constellation.yml:10: output condition in api.local has source \"STDIN\".  Must be one of [STDOUT STDERR]",
+				"constellation.yml:12: state condition in api.local has status \"sucess\".  Must be one of [success failure]",
+				"constellation.yml:14: container api.local: Invalid via hots.  Must be one of [pod host]",
+				"constellation.yml:18: container api.local: Invalid restart policy sometimes.  Must be one of [never on-failure always]",
+				"constellation.yml:20: container api.local: Invalid liveness action restart.  Must be one of [report stop fail]",
+			},
+		},
+		{
+			name: "cycles",
+			config: `
+containers:
+  a.local:
+    image: a:1
+    depends_on: [b.local]
+  b.local:
+    image: b:1
+    depends_on: [a.local]
+`,
+			expected: []string{"constellation.yml:5: dependency cycle between a.local, b.local"},
+		},
+		{
+			name: "decoding",
+			config: `
+containers:
+  api.local:
+    image: [api, 1]
+    state_conditions:
+      output:
+        - source: STDOUT
+          regex: yes
+          status: success
+        - source: STDOUT
+          status: success
+      timeout:
+        duration: soon
+        status: failure
+  empty.local:
+  db.local:
+    image: postgres:10
+    depends_on: [missing.local]
+`,
+			expected: []string{
+				"constellation.yml:4: container api.local: image must be a string, not a list",
+				"constellation.yml:8: container api.local: regex must be a string, not true or false.  Quote it if it is meant to be a string",
+				"constellation.yml:10: container api.local: Invalid output condition for STDOUT: One of regex or sequence must be set",
+				"constellation.yml:13: container api.local: duration must be a whole number, not a string",
+				"constellation.yml:15: container empty.local has no definition",
+				"constellation.yml:18: container db.local depends on missing.local which does not exist in the config",
+			},
+		},
+		{
+			name: "yaml",
+			config: `
+containers:
+  db.local:
+    image: postgres:10
+   bad: indent
+`,
+			expected: []string{"constellation.yml:4: error converting YAML to JSON: yaml: line 4: did not find expected key"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, problems := loadTestConfig(t, map[string]string{"constellation.yml": test.config}, nil, nil)
+			assertProblems(t, problems, test.expected...)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// variableReference matches ${NAME} and ${NAME:-default}, along with $${ which escapes a reference.  Names can't contain
+// dots, so references to captures (${db.captures.port}) are left for the container to fill in.
+var variableReference = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:-)([^}]*))?\}`)
+
+// envFileLine matches a NAME=value line in an env file
+var envFileLine = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// LoadEnvFile will read variables from a file of NAME=value lines.  Blank lines and lines starting with # are ignored,
+// and values can be wrapped in quotes.
+func LoadEnvFile(fileName string) (map[string]string, error) {
+	variables := make(map[string]string)
+	file, err := os.Open(fileName)
+	if err != nil {
+		return variables, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		match := envFileLine.FindStringSubmatch(line)
+		if match == nil {
+			return variables, errors.New(fmt.Sprintf("%s:%d: expected NAME=value", fileName, number))
+		}
+		value := match[2]
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		variables[match[1]] = value
+	}
+	return variables, scanner.Err()
+}
+
+// fileVariables pulls the variables stanza out of the raw value of a config file.  Values have to be strings, since
+// yaml would otherwise turn something like 1.10 into 1.1.  Those that aren't are reported and removed from raw, so that
+// they are not reported again when it is unmarshalled
+func fileVariables(src source, raw interface{}) (map[string]string, Problems) {
+	variables := make(map[string]string)
+	problems := make(Problems, 0)
+	top, ok := raw.(map[string]interface{})
+	if !ok {
+		return variables, problems
+	}
+	stanza, ok := top["variables"].(map[string]interface{})
+	if !ok {
+		return variables, problems
+	}
+	for _, name := range sortedKeys(stanza) {
+		value, ok := stanza[name].(string)
+		if !ok {
+			problems = append(problems, src.problem(joinPath("variables", name), "variable %s must be a string.  Quote it", name))
+			delete(stanza, name)
+			continue
+		}
+		variables[name] = value
+	}
+	return variables, problems
+}
+
+// interpolate will substitute variables into every string in the raw value of a config file.  Variables are looked up
+// in the environment first, and then in variables.  The variables stanza itself is left as it is.
+func interpolate(src source, value interface{}, variables map[string]string, path string) (interface{}, Problems) {
+	problems := make(Problems, 0)
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(typed) {
+			if path == "" && key == "variables" {
+				continue
+			}
+			var itemProblems Problems
+			typed[key], itemProblems = interpolate(src, typed[key], variables, joinPath(path, key))
+			problems = append(problems, itemProblems...)
+		}
+	case []interface{}:
+		for index := range typed {
+			var itemProblems Problems
+			typed[index], itemProblems = interpolate(src, typed[index], variables, joinPath(path, strconv.Itoa(index)))
+			problems = append(problems, itemProblems...)
+		}
+	case string:
+		var err error
+		value, err = substituteVariables(typed, variables)
+		if err != nil {
+			problems = append(problems, src.problem(path, "%s", err))
+		}
+	}
+	return value, problems
+}
+
+// substituteVariables replaces each variable reference in value
+func substituteVariables(value string, variables map[string]string) (string, error) {
+	var result bytes.Buffer
+	last := 0
+	for _, match := range variableReference.FindAllStringSubmatchIndex(value, -1) {
+		result.WriteString(value[last:match[0]])
+		last = match[1]
+		// $${ is just ${
+		if match[2] < 0 {
+			result.WriteString("${")
+			continue
+		}
+
+		name := value[match[2]:match[3]]
+		found, ok := os.LookupEnv(name)
+		if !ok {
+			found, ok = variables[name]
+		}
+		// a default is used if the variable is not set or is empty
+		if match[4] >= 0 && found == "" {
+			found, ok = value[match[6]:match[7]], true
+		}
+		if !ok {
+			return value, errors.New(fmt.Sprintf("variable %s is not set", name))
+		}
+		result.WriteString(found)
+	}
+	result.WriteString(value[last:])
+	return result.String(), nil
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSubstituteVariables(t *testing.T) {
+	t.Setenv("CONSTELLATION_TEST_ENV", "from-env")
+	t.Setenv("CONSTELLATION_TEST_EMPTY", "")
+	variables := map[string]string{"TAG": "1.4", "CONSTELLATION_TEST_ENV": "from-variables", "EMPTY": ""}
+
+	tests := []struct {
+		value    string
+		expected string
+		err      string
+	}{
+		{value: "api:${TAG}", expected: "api:1.4"},
+		{value: "${TAG}-${TAG}", expected: "1.4-1.4"},
+		{value: "${CONSTELLATION_TEST_ENV}", expected: "from-env"},
+		{value: "${LEVEL:-info}", expected: "info"},
+		{value: "${TAG:-latest}", expected: "1.4"},
+		{value: "${EMPTY:-default}", expected: "default"},
+		{value: "${CONSTELLATION_TEST_EMPTY:-default}", expected: "default"},
+		{value: "${EMPTY}", expected: ""},
+		{value: "$${TAG} costs $5", expected: "${TAG} costs $5"},
+		{value: "${db.local.captures.port}", expected: "${db.local.captures.port}"},
+		{value: "${MISSING}", err: "variable MISSING is not set"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			result, err := substituteVariables(test.value, variables)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Errorf("substituted %q, expected %q", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestVariablePrecedence(t *testing.T) {
+	t.Setenv("CONSTELLATION_TEST_ENV", "environment")
+	files := map[string]string{
+		"constellation.yml": `
+require:
+  - required.yml
+variables:
+  CONSTELLATION_TEST_ENV: file
+  PASSED: file
+  PARENT: file
+containers:
+  app.local:
+    image: app:1
+    environment:
+      ENV: ${CONSTELLATION_TEST_ENV}
+      PASSED: ${PASSED}
+      PARENT: ${PARENT}
+`,
+		"required.yml": `
+variables:
+  PARENT: required
+  OWN: required
+containers:
+  required.local:
+    image: required:1
+    environment:
+      PARENT: ${PARENT}
+      OWN: ${OWN}
+      PASSED: ${PASSED}
+`,
+	}
+	config, problems := loadTestConfig(t, files, nil, map[string]string{"PASSED": "passed"})
+	assertProblems(t, problems)
+
+	tests := []struct {
+		container string
+		expected  map[string]string
+	}{
+		{
+			container: "app.local",
+			expected:  map[string]string{"ENV": "environment", "PASSED": "passed", "PARENT": "file"},
+		},
+		{
+			container: "required.local",
+			expected:  map[string]string{"PARENT": "file", "OWN": "required", "PASSED": "passed"},
+		},
+	}
+	for _, test := range tests {
+		environment := config.Containers[test.container].Environment
+		if !reflect.DeepEqual(environment, test.expected) {
+			t.Errorf("%s has environment %v, expected %v", test.container, environment, test.expected)
+		}
+	}
+}
+
+func TestVariableProblems(t *testing.T) {
+	_, problems := loadTestConfig(t, map[string]string{"constellation.yml": `
+variables:
+  VERSION: 1.10
+containers:
+  app.local:
+    image: app:${VERSION}
+    environment:
+      LEVEL: ${LEVEL}
+`}, nil, nil)
+	assertProblems(t, problems,
+		"constellation.yml:3: variable VERSION must be a string.  Quote it",
+		"constellation.yml:6: variable VERSION is not set",
+		"constellation.yml:8: variable LEVEL is not set")
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected map[string]string
+		err      string
+	}{
+		{
+			name: "values",
+			contents: `# settings
+TAG=1.4
+
+export LEVEL=debug
+QUOTED="a value"
+SINGLE='another value'
+EMPTY=`,
+			expected: map[string]string{"TAG": "1.4", "LEVEL": "debug", "QUOTED": "a value", "SINGLE": "another value", "EMPTY": ""},
+		},
+		{
+			name:     "bad line",
+			contents: "TAG=1.4\nnot a variable",
+			err:      ":2: expected NAME=value",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "test.env")
+			err := ioutil.WriteFile(fileName, []byte(test.contents), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			variables, err := LoadEnvFile(fileName)
+			if test.err != "" {
+				if err == nil || err.Error() != fileName+test.err {
+					t.Fatalf("expected %q, got %v", fileName+test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(variables, test.expected) {
+				t.Errorf("loaded %v, expected %v", variables, test.expected)
+			}
+		})
+	}
+	if _, err := LoadEnvFile(filepath.Join(os.TempDir(), "constellation-missing.env")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,46 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/state"
+	"github.com/dansteen/constellation/types"
+)
+
+// addressResolver generates a state.AddressResolver for this container.  Addresses via the pod are looked up from the runtime
+// each time since the pod may not have a network until some time after it is started.
+func (container *Container) addressResolver(rt runtime.Runtime, projectName string, appName string) state.AddressResolver {
+	return func(portName string, via string) (string, error) {
+		port, err := container.getPort(portName)
+		if err != nil {
+			return "", err
+		}
+		switch via {
+		case "host":
+			return fmt.Sprintf("127.0.0.1:%d", port.HostPort), nil
+		case "pod":
+			runningPods, err := rt.GetRunningPods(projectName)
+			if err != nil {
+				return "", err
+			}
+			pod, ok := runningPods.Pods[appName]
+			if !ok || len(pod.Networks) == 0 || pod.Networks[0].IP == "" {
+				return "", errors.New(fmt.Sprintf("%s does not have an IP yet", container.Name))
+			}
+			return fmt.Sprintf("%s:%d", pod.Networks[0].IP, port.Port), nil
+		}
+		return "", errors.New(fmt.Sprintf("Unknown address type %s.  Must be pod or host", via))
+	}
+}
+
+// getPort will return the port from our image manifest with the provided name
+func (container *Container) getPort(portName string) (*types.Port, error) {
+	for _, port := range container.Ports {
+		if port.Name == portName {
+			return port, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("%s does not have a port named %s", container.Name, portName))
+}
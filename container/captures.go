@@ -0,0 +1,76 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// captureReference matches references to the captures of other containers, e.g. ${api.local.captures.token}
+var captureReference = regexp.MustCompile(`\$\{([^}]+)\.captures\.(\w+)\}`)
+
+// captureStore holds the named capture groups matched by the output and filemonitor conditions of a container.  They
+// are written from several handlers at once so access is locked.
+type captureStore struct {
+	lock     sync.Mutex
+	captures map[string]string
+}
+
+// recordCaptures will save captures for use by containers that depend on us
+func (container *Container) recordCaptures(captures map[string]string) {
+	container.captures.lock.Lock()
+	defer container.captures.lock.Unlock()
+	if container.captures.captures == nil {
+		container.captures.captures = make(map[string]string)
+	}
+	for name, value := range captures {
+		container.captures.captures[name] = value
+	}
+}
+
+// Captures returns a copy of the named capture groups this container has matched so far
+func (container *Container) Captures() map[string]string {
+	container.captures.lock.Lock()
+	defer container.captures.lock.Unlock()
+	captures := make(map[string]string)
+	for name, value := range container.captures.captures {
+		captures[name] = value
+	}
+	return captures
+}
+
+// substituteCaptures will replace any references to the captures of our dependencies in value.  Only containers we
+// depend on (directly or further down the chain) can be referenced, since they are the only ones guaranteed to be up.
+func (container *Container) substituteCaptures(value string) (string, error) {
+	var err error
+	result := captureReference.ReplaceAllStringFunc(value, func(reference string) string {
+		parts := captureReference.FindStringSubmatch(reference)
+		depName, captureName := parts[1], parts[2]
+		dependency := container.findDependency(depName)
+		if dependency == nil {
+			err = errors.New(fmt.Sprintf("%s references %s but does not depend on %s", container.Name, reference, depName))
+			return reference
+		}
+		captured, ok := dependency.Captures()[captureName]
+		if !ok {
+			err = errors.New(fmt.Sprintf("%s references %s but %s has not captured %s", container.Name, reference, depName, captureName))
+			return reference
+		}
+		return captured
+	})
+	return result, err
+}
+
+// findDependency will look for name in our dependency chain
+func (container *Container) findDependency(name string) *Container {
+	for depName, dependency := range container.DependsOn {
+		if depName == name {
+			return dependency
+		}
+		if found := dependency.findDependency(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
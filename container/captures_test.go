@@ -0,0 +1,72 @@
+package container
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubstituteCaptures(t *testing.T) {
+	db := &Container{Name: "db.local", DependsOn: map[string]*Container{}}
+	db.recordCaptures(map[string]string{"port": "5432"})
+	auth := &Container{Name: "auth.local", DependsOn: map[string]*Container{"db.local": db}}
+	auth.recordCaptures(map[string]string{"token": "abc 123"})
+	cache := &Container{Name: "cache.local", DependsOn: map[string]*Container{}}
+	cache.recordCaptures(map[string]string{"port": "6379"})
+	app := &Container{Name: "app.local", DependsOn: map[string]*Container{"auth.local": auth}}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		err      string
+	}{
+		{
+			name:     "direct dependency",
+			value:    "--token=${auth.local.captures.token}",
+			expected: "--token=abc 123",
+		},
+		{
+			name:     "further down the chain",
+			value:    "postgres://db:${db.local.captures.port}/app",
+			expected: "postgres://db:5432/app",
+		},
+		{
+			name:     "several",
+			value:    "${auth.local.captures.token}@${db.local.captures.port}",
+			expected: "abc 123@5432",
+		},
+		{
+			name:     "nothing to substitute",
+			value:    "${HOME}/app",
+			expected: "${HOME}/app",
+		},
+		{
+			name:  "not a dependency",
+			value: "${cache.local.captures.port}",
+			err:   "app.local references ${cache.local.captures.port} but does not depend on cache.local",
+		},
+		{
+			name:  "not captured",
+			value: "${auth.local.captures.user}",
+			err:   "app.local references ${auth.local.captures.user} but auth.local has not captured user",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := app.substituteCaptures(test.value)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Errorf("substituted %q, expected %q", result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/state"
+)
+
+// commandRunner generates a state.CommandRunner that runs commands inside the running pod of this container
+func (container *Container) commandRunner(rt runtime.Runtime, projectName string, appName string) state.CommandRunner {
+	return func(command []string, timeout time.Duration) (int, error) {
+		runningPods, err := rt.GetRunningPods(projectName)
+		if err != nil {
+			return -1, err
+		}
+		pod, ok := runningPods.Pods[appName]
+		if !ok {
+			return -1, errors.New(fmt.Sprintf("%s is not running", container.Name))
+		}
+		execCommand, err := rt.ExecCommand(pod, appName, command)
+		if err != nil {
+			return -1, err
+		}
+		err = execCommand.Start()
+		if err != nil {
+			return -1, err
+		}
+
+		// wait for our command, killing it if it takes too long
+		done := make(chan error, 1)
+		go func() {
+			done <- execCommand.Wait()
+		}()
+		select {
+		case err = <-done:
+		case <-time.After(timeout):
+			execCommand.Process.Kill()
+			<-done
+			return -1, errors.New(fmt.Sprintf("%+v timed out", command))
+		}
+		if err == nil {
+			return 0, nil
+		}
+		if exiterr, ok := err.(*exec.ExitError); ok {
+			if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
+				return status.ExitStatus(), nil
+			}
+		}
+		return -1, err
+	}
+}
@@ -0,0 +1,204 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/dansteen/constellation/state"
+)
+
+// conditionContext holds everything the handlers for our state conditions need during a single run
+type conditionContext struct {
+	// waiter is nil if we did not start the pod ourselves (e.g. it was already running)
+	waiter   *state.ExitWaiter
+	resolver state.AddressResolver
+	runner   state.CommandRunner
+	logger   *log.Logger
+	// outputs are fed lines by handleOutput, since we can only tap into the outputs a single time.  liveness conditions
+	// add to these while the output is being handled so access is locked
+	outputs     []*outputWatcher
+	outputsLock sync.Mutex
+}
+
+// outputWatcher is a single output condition waiting on lines from handleOutput
+type outputWatcher struct {
+	condition state.OutputCondition
+	results   chan<- error
+	stop      <-chan bool
+	done      bool
+}
+
+// startConditions will start the handlers for a block of state conditions.  Every handler reports to status, and the
+// first one to do so decides the result of the block.  Handlers give up once stop is closed.  status must be buffered
+// to hold at least conditions.Count() results so that late handlers never block.
+func (container *Container) startConditions(conditions *state.StateConditions, ctx *conditionContext, status chan<- error, stop <-chan bool) {
+	// an empty block has nothing to wait for
+	if conditions.Count() == 0 {
+		status <- nil
+		return
+	}
+
+	// handle timeouts if set
+	if conditions.Timeout != nil {
+		go conditions.Timeout.Handle(status, stop, ctx.logger)
+	}
+
+	// handle log monitors if set (must happen before command is started)
+	for _, monitor := range conditions.FileMonitors {
+		go func(monitor state.FileMonitorCondition) {
+			monitor.OnCapture(container.recordCaptures)
+			monitor.Handle(status, stop, ctx.logger)
+		}(monitor)
+	}
+
+	// output conditions are handled by handleOutput
+	for _, condition := range conditions.Outputs {
+		ctx.logger.Printf("Monitoring %s for %s\n", condition.Source, condition.Matcher)
+		condition.OnCapture(container.recordCaptures)
+		ctx.outputsLock.Lock()
+		ctx.outputs = append(ctx.outputs, &outputWatcher{condition: condition, results: status, stop: stop})
+		ctx.outputsLock.Unlock()
+	}
+
+	// handle http and tcp conditions if set.  these retry until the pod exists
+	for _, condition := range conditions.HTTP {
+		go func(condition state.HTTPCondition) {
+			condition.Handle(ctx.resolver, status, stop, ctx.logger)
+		}(condition)
+	}
+	for _, condition := range conditions.TCP {
+		go func(condition state.TCPCondition) {
+			condition.Handle(ctx.resolver, status, stop, ctx.logger)
+		}(condition)
+	}
+
+	// handle command conditions if set
+	for _, condition := range conditions.Commands {
+		go func(condition state.CommandCondition) {
+			condition.Handle(ctx.runner, status, stop, ctx.logger)
+		}(condition)
+	}
+
+	// handle exit conditions if set.  we can only tell when a pod exits if we started it
+	if conditions.Exit != nil {
+		if ctx.waiter != nil {
+			go conditions.Exit.Handle(ctx.waiter, status, stop, ctx.logger)
+		} else {
+			ctx.logger.Printf("Not started by this run.  Ignoring exit condition %+v\n", conditions.Exit.Codes)
+		}
+	}
+
+	// and finally any nested blocks.  these are started right away so that their output conditions are in place before
+	// the command starts
+	if len(conditions.All) > 0 {
+		container.handleGroup(conditions.All, true, ctx, status, stop)
+	}
+	if len(conditions.Any) > 0 {
+		container.handleGroup(conditions.Any, false, ctx, status, stop)
+	}
+	if conditions.Not != nil {
+		container.handleNot(conditions.Not, ctx, status, stop)
+	}
+}
+
+// startBlock will start a nested block of state conditions and return the channel its result will be sent to.  The
+// block is stopped once it has a result or once stop is closed.
+func (container *Container) startBlock(conditions *state.StateConditions, ctx *conditionContext, stop <-chan bool) <-chan error {
+	blockStatus := make(chan error, conditions.Count()+1)
+	blockStop := make(chan bool)
+	container.startConditions(conditions, ctx, blockStatus, blockStop)
+
+	result := make(chan error, 1)
+	go func() {
+		select {
+		case err := <-blockStatus:
+			close(blockStop)
+			result <- err
+		case <-stop:
+			close(blockStop)
+		}
+	}()
+	return result
+}
+
+// handleGroup will run each block in an all or any group and combine their results.  An all group succeeds once every
+// block has succeeded and fails as soon as one fails.  An any group succeeds as soon as one block succeeds and fails
+// once every block has failed.
+func (container *Container) handleGroup(blocks []state.StateConditions, all bool, ctx *conditionContext, status chan<- error, stop <-chan bool) {
+	// gather the results of our blocks into one place
+	results := make(chan error, len(blocks))
+	for index := range blocks {
+		go func(blockResult <-chan error) {
+			select {
+			case err := <-blockResult:
+				results <- err
+			case <-stop:
+			}
+		}(container.startBlock(&blocks[index], ctx, stop))
+	}
+
+	go func() {
+		var lastErr error
+		for remaining := len(blocks); remaining > 0; remaining-- {
+			select {
+			case err := <-results:
+				if all && err != nil {
+					status <- err
+					return
+				}
+				if !all && err == nil {
+					status <- nil
+					return
+				}
+				lastErr = err
+			case <-stop:
+				return
+			}
+		}
+		if all {
+			status <- nil
+		} else {
+			status <- errors.New(fmt.Sprintf("None of the blocks in an any condition succeeded. Last failure: %s", lastErr))
+		}
+	}()
+}
+
+// handleNot will run a block and invert its result
+func (container *Container) handleNot(block *state.StateConditions, ctx *conditionContext, status chan<- error, stop <-chan bool) {
+	blockResult := container.startBlock(block, ctx, stop)
+	go func() {
+		select {
+		case err := <-blockResult:
+			if err == nil {
+				status <- errors.New("Conditions in a not block succeeded. Specified as failure\n")
+			} else {
+				ctx.logger.Printf("Conditions in a not block failed (%s). Specified as success\n", err)
+				status <- nil
+			}
+		case <-stop:
+		}
+	}()
+}
+
+// watchers returns the output watchers for source that have not been stopped.  Stopped watchers are dropped
+func (ctx *conditionContext) watchers(source string) []*outputWatcher {
+	ctx.outputsLock.Lock()
+	defer ctx.outputsLock.Unlock()
+	active := make([]*outputWatcher, 0)
+	watchers := make([]*outputWatcher, 0)
+	for _, watcher := range ctx.outputs {
+		select {
+		case <-watcher.stop:
+			continue
+		default:
+		}
+		active = append(active, watcher)
+		if watcher.condition.Source == source {
+			watchers = append(watchers, watcher)
+		}
+	}
+	ctx.outputs = active
+	return watchers
+}
@@ -0,0 +1,183 @@
+package container
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dansteen/constellation/state"
+)
+
+// startTestConditions unmarshals conditions and starts them.  The returned function feeds lines to their output
+// conditions the way our STDOUT would
+func startTestConditions(t *testing.T, conditions string) (<-chan error, func(lines ...string)) {
+	var stateConditions state.StateConditions
+	err := json.Unmarshal([]byte(conditions), &stateConditions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	ctx := &conditionContext{logger: logger}
+	container := &Container{Name: "test"}
+	status := make(chan error, stateConditions.Count())
+	stop := make(chan bool)
+	t.Cleanup(func() { close(stop) })
+	container.startConditions(&stateConditions, ctx, status, stop)
+
+	output := func(lines ...string) {
+		scanner := bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))
+		container.handleOutput(scanner, "STDOUT", ctx, logger)
+	}
+	return status, output
+}
+
+// outputCondition is the json of an output condition on STDOUT
+func outputCondition(regex string, status string) string {
+	return `{"output": [{"source": "STDOUT", "regex": "` + regex + `", "status": "` + status + `"}]}`
+}
+
+func TestCompositeConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions string
+		lines      []string
+		// pending is true if there should not be a result yet
+		pending bool
+		err     string
+	}{
+		{
+			name:       "all succeeds once every block has",
+			conditions: `{"all": [` + outputCondition("first", "success") + `, ` + outputCondition("second", "success") + `]}`,
+			lines:      []string{"second", "first"},
+		},
+		{
+			name:       "all waits for every block",
+			conditions: `{"all": [` + outputCondition("first", "success") + `, ` + outputCondition("second", "success") + `]}`,
+			lines:      []string{"first"},
+			pending:    true,
+		},
+		{
+			name:       "all fails once one block fails",
+			conditions: `{"all": [` + outputCondition("ready", "success") + `, ` + outputCondition("panic", "failure") + `]}`,
+			lines:      []string{"panic"},
+			err:        "STDOUT matched panic. Specified as failure",
+		},
+		{
+			name:       "any succeeds once one block has",
+			conditions: `{"any": [` + outputCondition("first", "success") + `, ` + outputCondition("second", "success") + `]}`,
+			lines:      []string{"second"},
+		},
+		{
+			name:       "any fails once every block has",
+			conditions: `{"any": [` + outputCondition("panic", "failure") + `, ` + outputCondition("fatal", "failure") + `]}`,
+			lines:      []string{"panic", "fatal"},
+			err:        "None of the blocks in an any condition succeeded",
+		},
+		{
+			name:       "any waits for every block to fail",
+			conditions: `{"any": [` + outputCondition("panic", "failure") + `, ` + outputCondition("fatal", "failure") + `]}`,
+			lines:      []string{"panic"},
+			pending:    true,
+		},
+		{
+			name:       "not fails when its block succeeds",
+			conditions: `{"not": ` + outputCondition("ready", "success") + `}`,
+			lines:      []string{"ready"},
+			err:        "Conditions in a not block succeeded",
+		},
+		{
+			name:       "not succeeds when its block fails",
+			conditions: `{"not": ` + outputCondition("panic", "failure") + `}`,
+			lines:      []string{"panic"},
+		},
+		{
+			name: "nested",
+			conditions: `{"all": [{"any": [` + outputCondition("primary", "success") + `, ` + outputCondition("replica", "success") +
+				`]}, ` + outputCondition("migrated", "success") + `]}`,
+			lines: []string{"replica", "migrated"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, output := startTestConditions(t, test.conditions)
+			output(test.lines...)
+
+			wait := 5 * time.Second
+			if test.pending {
+				wait = 200 * time.Millisecond
+			}
+			select {
+			case err := <-status:
+				if test.pending {
+					t.Errorf("expected no result yet, got %v", err)
+				} else if test.err == "" && err != nil {
+					t.Errorf("expected success, got %v", err)
+				} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+					t.Errorf("expected an error containing %q, got %v", test.err, err)
+				}
+			case <-time.After(wait):
+				if !test.pending {
+					t.Error("no result was reported")
+				}
+			}
+		})
+	}
+}
+
+func TestTransient(t *testing.T) {
+	exitSuccess := `{"exit": {"codes": [0], "status": "success"}}`
+	tests := []struct {
+		name       string
+		conditions string
+		transient  bool
+	}{
+		{
+			name:       "exit success",
+			conditions: exitSuccess,
+			transient:  true,
+		},
+		{
+			name:       "exit failure",
+			conditions: `{"exit": {"codes": [1], "status": "failure"}}`,
+		},
+		{
+			name:       "no exit",
+			conditions: outputCondition("ready", "success"),
+		},
+		{
+			name:       "in an all block",
+			conditions: `{"all": [` + outputCondition("ready", "success") + `, {"all": [` + exitSuccess + `]}]}`,
+			transient:  true,
+		},
+		{
+			name:       "in an any block",
+			conditions: `{"any": [` + outputCondition("ready", "success") + `, ` + exitSuccess + `]}`,
+		},
+		{
+			name:       "in a not block",
+			conditions: `{"not": ` + exitSuccess + `}`,
+		},
+		{
+			name:       "in an all block in an any block",
+			conditions: `{"any": [{"all": [` + exitSuccess + `]}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			container := &Container{Name: "test"}
+			err := json.Unmarshal([]byte(test.conditions), &container.StateConditions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if container.Transient() != test.transient {
+				t.Errorf("Transient() is %t, expected %t", container.Transient(), test.transient)
+			}
+		})
+	}
+}
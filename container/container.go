@@ -9,8 +9,10 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"syscall"
 
-	"github.com/dansteen/constellation/rkt"
+	"github.com/dansteen/constellation/runtime"
 	"github.com/dansteen/constellation/state"
 	"github.com/dansteen/constellation/types"
 	"github.com/dansteen/constellation/util"
@@ -20,21 +22,39 @@ import (
 
 // Container stores all the information about a container to operate on
 type Container struct {
-	Name            string
-	ImageHash       string
+	Name            string                `json:"-"`
+	ImageHash       string                `json:"-"`
 	Image           string                `json:"image"`
 	Environment     map[string]string     `json:"environment"`
 	Exec            string                `json:"exec"`
 	StateConditions state.StateConditions `json:"state_conditions"`
-	Mounts          []Mount               `json:"mounts"`
+	Mounts          []types.Mount         `json:"mounts"`
 	DependsStrings  []string              `json:"depends_on"`
-	DependsOn       map[string]*Container
-	Ports           []*Port
+	LogFile         string                `json:"log_file"`
+	Liveness        *Liveness             `json:"liveness"`
+	Restart         *RestartPolicy        `json:"restart"`
+	Extends         *Extends              `json:"extends"`
+	Profiles        []string              `json:"profiles"`
+	DependsOn       map[string]*Container `json:"-"`
+	Ports           []*types.Port         `json:"-"`
+
+	// logFile is where we save the output of this container when LogFile is set.  logLock guards it, since our output
+	// handlers keep writing to it until it is closed
+	logFile *util.RotatingFile
+	logLock sync.Mutex
+	// captures holds the named capture groups matched by our output and filemonitor conditions
+	captures captureStore
+	// conditions is what our state conditions (and liveness conditions) need while we run
+	conditions *conditionContext
+	// logger is where we log everything about this container
+	logger *log.Logger
+	// restarts is the number of times we have been restarted
+	restarts int
 }
 
-// Init will do the inital checking of a container to make sure it's viable.  We also pull the images.
-// We can also do initial container setup here if we want (though we don't right now)
-func (container *Container) Init(containers map[string]*Container, volumes map[string]types.Volume) error {
+// Init will do the inital checking of a container to make sure it's viable.  We also pull the images and open the log
+// file, so SetLogFile must be called first.  CloseLogFile should be called once the container is no longer needed.
+func (container *Container) Init(rt runtime.Runtime, containers map[string]*Container, volumes map[string]types.Volume) error {
 
 	// Make sure that any mounts reference defined volumes
 	for _, mount := range container.Mounts {
@@ -43,24 +63,45 @@ func (container *Container) Init(containers map[string]*Container, volumes map[s
 		}
 	}
 
+	// make sure our restart policy makes sense
+	if container.Restart != nil {
+		err := container.Restart.Validate()
+		if err != nil {
+			return errors.New(fmt.Sprintf("%s: %s", container.Name, err))
+		}
+	}
+
+	// make sure our liveness block makes sense
+	if container.Liveness != nil {
+		err := container.Liveness.Validate()
+		if err != nil {
+			return errors.New(fmt.Sprintf("%s: %s", container.Name, err))
+		}
+	}
+
 	// make sure that any filemonitors reference paths that are mounted from the filesystem.  Otherwie the filemonitor will
 	// never trigger since it runs outside of the container
-	for index, condition := range container.StateConditions.FileMonitors {
-		found := false
-		for _, mount := range container.Mounts {
-			if strings.HasPrefix(condition.File, mount.Path) {
-				found = true
-				// replace the prefix with the respective local path
-				localPath := strings.Replace(condition.File, mount.Path, volumes[mount.Volume].Path, 1)
-				container.StateConditions.FileMonitors[index] = state.FileMonitorCondition{
-					File:   localPath,
-					Regex:  condition.Regex,
-					Status: condition.Status,
+	for _, block := range container.conditionBlocks() {
+		err := block.Walk(func(conditions *state.StateConditions) error {
+			for index, condition := range conditions.FileMonitors {
+				found := false
+				for _, mount := range container.Mounts {
+					if strings.HasPrefix(condition.File, mount.Path) {
+						found = true
+						// replace the prefix with the respective local path
+						localPath := strings.Replace(condition.File, mount.Path, volumes[mount.Volume].Path, 1)
+						condition.File = localPath
+						conditions.FileMonitors[index] = condition
+					}
+				}
+				if found == false {
+					return errors.New(fmt.Sprintf("File monitor requests a path (%s) that is not prefixed by any mount path", condition.File))
 				}
 			}
-		}
-		if found == false {
-			return errors.New(fmt.Sprintf("File monitor requests a path (%s) that is not prefixed by any mount path", condition.File))
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -76,36 +117,82 @@ func (container *Container) Init(containers map[string]*Container, volumes map[s
 	}
 	container.DependsOn = depends
 
+	// make sure any captures we reference are from containers that exist.  whether we actually depend on them can only be
+	// checked once every container has been linked up, so that happens when we run
+	references := captureReference.FindAllStringSubmatch(container.Exec, -1)
+	for _, value := range container.Environment {
+		references = append(references, captureReference.FindAllStringSubmatch(value, -1)...)
+	}
+	for _, reference := range references {
+		if _, ok := containers[reference[1]]; !ok {
+			return errors.New(fmt.Sprintf("%s references %s but %s does not exist in the config.", container.Name, reference[0], reference[1]))
+		}
+	}
+
 	// pull our image
-	imageHash, err := rkt.Fetch(container.Image)
+	imageHash, err := rt.Fetch(container.Image)
 	if err != nil {
 		return err
 	}
 	container.ImageHash = imageHash
 
 	// initialize our port list
-	container.Ports = make([]*Port, 0)
+	container.Ports = make([]*types.Port, 0)
 	// grab our image manifest (we use the hash hear because cat-manifest doesn't like docker images
-	imageManifest, err := rkt.GetImageManifest(container.ImageHash)
+	imageManifest, err := rt.GetImageManifest(container.ImageHash)
 	if err != nil {
 		log.Printf("Could not get manifest of image: %s", container.ImageHash)
 		return err
 	}
 	// and add the ports to our container
 	for _, manifestPort := range imageManifest.App.Ports {
-		container.Ports = append(container.Ports, &Port{ImageAppPort: manifestPort})
+		container.Ports = append(container.Ports, &types.Port{ImageAppPort: manifestPort})
 	}
 
-	return nil
+	// now that we know our ports, make sure any http and tcp conditions reference ports we have
+	for _, block := range container.conditionBlocks() {
+		err := block.Walk(func(conditions *state.StateConditions) error {
+			for _, condition := range conditions.HTTP {
+				if _, err := container.getPort(condition.Port); err != nil {
+					return err
+				}
+			}
+			for _, condition := range conditions.TCP {
+				if _, err := container.getPort(condition.Port); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
 
+	// open our log file if we are saving our output.  it is shared by every run of this container, including restarts
+	return container.openLogFile()
 }
 
 // Run will run a container.  It will return an error message if the container fails by any of the containers StateConditions
-func (container *Container) Run(configPath string, projectName string, volumes map[string]types.Volume, hostsEntries []types.HostsEntry) error {
-	// set up logging for this run
-	colors := util.RandomColor()
-	ourColor := color.New(colors...).SprintfFunc()
-	logger := log.New(os.Stdout, fmt.Sprintf("[%s] ", ourColor(container.Name)), log.LstdFlags)
+// If it fails, it is restarted as long as its restart policy allows it.
+func (container *Container) Run(rt runtime.Runtime, configPath string, projectName string, volumes map[string]types.Volume, hostsEntries []types.HostsEntry) error {
+	// set up logging for this container.  we keep the same logger across restarts so our color stays the same
+	if container.logger == nil {
+		colors := util.RandomColor()
+		ourColor := color.New(colors...).SprintfFunc()
+		container.logger = log.New(os.Stdout, fmt.Sprintf("[%s] ", ourColor(container.Name)), log.LstdFlags)
+	}
+
+	err := container.run(rt, configPath, projectName, volumes, hostsEntries)
+	for err != nil && container.canRestart() {
+		err = container.restart(rt, configPath, projectName, volumes, hostsEntries, err)
+	}
+	return err
+}
+
+// run will make a single attempt at running a container
+func (container *Container) run(rt runtime.Runtime, configPath string, projectName string, volumes map[string]types.Volume, hostsEntries []types.HostsEntry) error {
+	logger := container.logger
 	logger.Printf("Running")
 
 	// set a result to start with
@@ -114,9 +201,20 @@ func (container *Container) Run(configPath string, projectName string, volumes m
 
 	// check to see if we are not already running a container with this project and name
 	// get our name
-	name, err := rkt.GetAppName(projectName, container.Name)
+	name, err := rt.GetAppName(projectName, container.Name)
+	if err != nil {
+		return err
+	}
+	// setup what our state conditions need
+	ctx := &conditionContext{
+		resolver: container.addressResolver(rt, projectName, name),
+		runner:   container.commandRunner(rt, projectName, name),
+		logger:   logger,
+	}
+	container.conditions = ctx
+
 	// get a list of running pods
-	runningPods, err := rkt.GetRunningPods(projectName)
+	runningPods, err := rt.GetRunningPods(projectName)
 	if err != nil {
 		return err
 	}
@@ -124,66 +222,62 @@ func (container *Container) Run(configPath string, projectName string, volumes m
 	for runningName, _ := range runningPods.Pods {
 		if runningName == name {
 			logger.Printf("Using already running container %s for %s.", runningName, container.Name)
+			// pick up anything it captured when it was started so our dependents can still use it
+			record, err := LoadRecord(configPath, container.Name)
+			if err != nil {
+				logger.Printf("Could not load record: %s", err)
+			}
+			container.recordCaptures(record.Captures)
 			return nil
 		}
 	}
 
-	// get our command line
-	commandLine, err := container.getCommandLine(projectName, runningPods, logger)
+	// get the spec for our pod
+	spec, err := container.getPodSpec(rt, configPath, projectName, runningPods, volumes, hostsEntries, logger)
 	if err != nil {
 		return err
 	}
 
-	// prefix volumes
-	for _, volume := range volumes {
-		commandLine = append(volume.GenerateCommandLine(), commandLine...)
-	}
-
-	// prefix hostsEntries
-	for _, entry := range hostsEntries {
-		commandLine = append(entry.GenerateCommandLine(), commandLine...)
-	}
-
-	// prefix our port maps
+	// set our port maps.  we do this as close to execution as possible to avoid conflicts
 	for _, entry := range container.Ports {
-		// we do this as close to execution as possible to avoid conflicts
 		err = entry.SetHostPort()
 		if err != nil {
 			return err
 		}
-		commandLine = append(entry.GenerateCommandLine(), commandLine...)
 	}
 
-	// prefix TODO: we want to allow settings for these
-	commandLine = append(strings.Split(fmt.Sprintf("rkt run --local-config=%s --dns=host", configPath), " "), commandLine...)
-
-	logger.Println(commandLine)
-	// set up our command run
-	command := exec.Command(commandLine[0], commandLine[1:]...)
-
-	// setup our state condition results
-	status := make(chan error)
-	// setup our stop channel to let state conditions know they don't need to continue, we buffer as many values as we have handlers
-	numHandlers := container.StateConditions.Count()
-	stop := make(chan bool, numHandlers)
-
-	// handle timeouts if set
-	if container.StateConditions.Timeout != nil {
-		go container.StateConditions.Timeout.Handle(status, stop, logger)
+	// and have the runtime generate our command
+	command, err := rt.RunCommand(spec)
+	if err != nil {
+		return err
 	}
-
-	// handle log monitors if set (must happen before command is started)
-	if len(container.StateConditions.FileMonitors) > 0 {
-		for _, monitor := range container.StateConditions.FileMonitors {
-			go func(monitor state.FileMonitorCondition, status chan error, stop chan bool, logger *log.Logger) {
-				monitor.Handle(status, stop, logger)
-			}(monitor, status, stop, logger)
+	logger.Println(command.Args)
+	// run our pod in its own process group so that signals sent to constellation (e.g. Ctrl-C) are not also delivered to
+	// our pods.  This lets us decide how and in what order they are stopped.
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	// setup our state condition results.  we buffer enough results for each of our handlers (plus our default exit
+	// handler) so that handlers that trigger after we have our result do not block
+	status := make(chan error, container.StateConditions.Count()+1)
+	// setup our stop channel to let state conditions know they don't need to continue
+	stop := make(chan bool)
+
+	// start our state condition handlers.  this must happen before the command is started so that file and output
+	// monitors see everything the command does
+	ctx.waiter = state.NewExitWaiter()
+	container.startConditions(&container.StateConditions, ctx, status, stop)
+	if !container.StateConditions.HasExit() {
+		// if we don't have an exit handler, we build a default one to fail on any exit
+		exitHandler := state.ExitCondition{
+			Codes:  []int{-1},
+			Status: "success",
 		}
+		go exitHandler.Handle(ctx.waiter, status, stop, logger)
 	}
 
 	// we want to both monitor and print outputs so we do things a bit different for this Handler.  This has to go prior to
 	// command.Start()
-	err = container.handleOutputs(command, status, stop, logger)
+	err = container.handleOutputs(command, ctx, logger)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -193,35 +287,31 @@ func (container *Container) Run(configPath string, projectName string, volumes m
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// handle exit conditions if set (must happen after the command is started)
-	if container.StateConditions.Exit != nil {
-		go container.StateConditions.Exit.Handle(command, status, stop, logger)
-	} else {
-		// if we don't have an exit handler, we build a default one to fail on any exit
-		exitHandler := state.ExitCondition{
-			Codes:  []int{-1},
-			Status: "success",
-		}
-		go exitHandler.Handle(command, status, stop, logger)
+	// record that we are starting up
+	err = Record{Name: container.Name, AppName: name, Ports: container.Ports, Result: "starting", Captures: container.Captures()}.save(configPath)
+	if err != nil {
+		logger.Printf("Could not save record: %s", err)
 	}
+	// let our exit conditions know once the command exits (must happen after the command is started)
+	go ctx.waiter.Wait(command)
 
 	// we wait for one of our conditions to return if we have any
 	if container.StateConditions.Count() != 0 {
 		result = <-status
 		// once one condition returns, we cancel the rest
 		close(stop)
-		close(status)
-		//for i := 0; i < numHandlers; i++ {
-		//	stop <- true
-		//}
 	}
 
+	// record the result of our run
+	err = container.SaveRecord(configPath, name, result)
+	if err != nil {
+		logger.Printf("Could not save record: %s", err)
+	}
 	return result
 }
 
 // handleOutputs will print the stderr and stdout of command
-func (container *Container) handleOutputs(command *exec.Cmd, results chan<- error, stop <-chan bool, logger *log.Logger) error {
+func (container *Container) handleOutputs(command *exec.Cmd, ctx *conditionContext, logger *log.Logger) error {
 
 	// process our outputs
 	// stdout
@@ -231,7 +321,7 @@ func (container *Container) handleOutputs(command *exec.Cmd, results chan<- erro
 		return errors.New("Could not connect to stdout")
 	}
 	outScanner := bufio.NewScanner(stdout)
-	go container.handleOutput(outScanner, "STDOUT", results, stop, logger)
+	go container.handleOutput(outScanner, "STDOUT", ctx, logger)
 
 	// stderr
 	stderr, err := command.StderrPipe()
@@ -240,118 +330,115 @@ func (container *Container) handleOutputs(command *exec.Cmd, results chan<- erro
 		return errors.New("Could not connect to stderr")
 	}
 	errScanner := bufio.NewScanner(stderr)
-	go container.handleOutput(errScanner, "STDERR", results, stop, logger)
+	go container.handleOutput(errScanner, "STDERR", ctx, logger)
 	return nil
 }
 
 // handleOutput does the heavy lifting for printOutputs.  Source is the source the log is coming from
-// this also activates the state condition handler for outputs since we can only tap into the outputs a single time
-func (container *Container) handleOutput(scanner *bufio.Scanner, source string, results chan<- error, stop <-chan bool, logger *log.Logger) {
+// this also feeds the output conditions watching this source since we can only tap into the outputs a single time
+func (container *Container) handleOutput(scanner *bufio.Scanner, source string, ctx *conditionContext, logger *log.Logger) {
 	// we print app messages a different color so they stand out
 	appMessage := color.New(color.FgWhite, color.BgBlack).SprintFunc()
 
-	// see if we need to run an output condition for this
-	conditions := make([]*state.OutputCondition, 0)
-	for _, outputCondition := range container.StateConditions.Outputs {
-		if outputCondition.Source == source {
-			logger.Printf("Monitoring %s for %+v\n", outputCondition.Source, outputCondition.Regex)
-			conditions = append(conditions, &outputCondition)
-		}
-	}
-
 	// first print our output
-	for {
-		// check if we still need to handle state conditions
-		select {
-		case <-stop:
-			conditions = make([]*state.OutputCondition, 0)
-		default:
-			moreContent := scanner.Scan()
-			// if we hit an error or eof we are done
-			if !moreContent {
-				logger.Println(scanner.Err())
-				return
+	for scanner.Scan() {
+		logger.Printf("%s", appMessage(scanner.Text()))
+		container.writeLog(source, scanner.Text())
+		// then hand the line to any conditions that still need it
+		for _, watcher := range ctx.watchers(source) {
+			if watcher.done {
+				continue
 			}
-			logger.Printf("%s", appMessage(scanner.Text()))
-			// if we need to handle the content
-			for _, condition := range conditions {
-				condition.Handle(scanner.Text(), results, stop, logger)
+			select {
+			case <-watcher.stop:
+				watcher.done = true
+			default:
+				watcher.done = watcher.condition.Handle(scanner.Text(), watcher.results, watcher.stop, logger)
 			}
 		}
 	}
+	// if we hit an error or eof we are done
+	logger.Println(scanner.Err())
 }
 
-// getCommandLine will generate rkt cli commands for this container
-func (container *Container) getCommandLine(projectName string, runningPods rkt.Pods, logger *log.Logger) ([]string, error) {
-	// generate the different components
-	command := make([]string, 0)
+// Transient checks if this container is expected to exit once it is done (e.g. a migration)
+func (container *Container) Transient() bool {
+	return exitsOnSuccess(&container.StateConditions)
+}
 
-	// get the appName
-	appName, err := rkt.GetAppName(projectName, container.Name)
-	if err != nil {
-		return command, err
+// exitsOnSuccess checks if conditions can only succeed by exiting.  That is the case when it has an exit condition with a
+// status of success, either itself or in one of its all blocks.  Exit conditions in any blocks are only one way to
+// succeed, and those in not blocks succeed when the exit condition does not, so neither of them count.
+func exitsOnSuccess(conditions *state.StateConditions) bool {
+	if conditions.Exit != nil && conditions.Exit.Status == "success" {
+		return true
 	}
-	appNameLine := fmt.Sprintf("--name=%s", appName)
+	for index := range conditions.All {
+		if exitsOnSuccess(&conditions.All[index]) {
+			return true
+		}
+	}
+	return false
+}
 
-	// generate environment strings
-	envArray := make([]string, 0)
-	for varName, varValue := range container.Environment {
-		envArray = append(envArray, fmt.Sprintf("--environment=%s=%s", varName, varValue))
+// getPodSpec will generate the runtime independent description of the pod for this container
+func (container *Container) getPodSpec(rt runtime.Runtime, configPath string, projectName string, runningPods types.Pods, volumes map[string]types.Volume, hostsEntries []types.HostsEntry, logger *log.Logger) (types.PodSpec, error) {
+	// get the appName
+	appName, err := rt.GetAppName(projectName, container.Name)
+	if err != nil {
+		return types.PodSpec{}, err
 	}
 
 	// exec string
 	execArray := make([]string, 0)
 	if container.Exec != "" {
-		exec_parts := util.ShellSplit(container.Exec)
-
-		if len(exec_parts) > 0 {
-			// first prime our array
-			execArray = append(execArray, "--exec")
-			// split our string into parts
-			execArray = append(execArray, exec_parts[0])
-		}
-		// if there is more than one part the rkt command requires that other compoments come after a double hyphen
-		if len(exec_parts) > 1 {
-			execArray = append(execArray, "--")
-			execArray = append(execArray, exec_parts[1:]...)
+		execArray = util.ShellSplit(container.Exec)
+	}
+	// fill in anything our dependencies captured.  we do this after splitting so captured values can contain spaces
+	for index, arg := range execArray {
+		execArray[index], err = container.substituteCaptures(arg)
+		if err != nil {
+			return types.PodSpec{}, err
 		}
 	}
-
-	// mount strings
-	mountArray := make([]string, 0)
-	for _, mount := range container.Mounts {
-		mountArray = append(mountArray, mount.GenerateCommandLine()...)
+	environment := make(map[string]string)
+	for key, value := range container.Environment {
+		environment[key], err = container.substituteCaptures(value)
+		if err != nil {
+			return types.PodSpec{}, err
+		}
 	}
 
-	depIPMap, err := container.GetDepChainIPs(projectName, runningPods, logger)
+	depIPMap, err := container.GetDepChainIPs(rt, projectName, runningPods, logger)
 	if err != nil {
-		return command, err
+		return types.PodSpec{}, err
 	}
 
-	hostsArray := make([]string, 0)
+	depHosts := make([]types.HostsEntry, 0)
 	for name, IPs := range depIPMap {
 		for _, IP := range IPs {
-			hostsArray = append(hostsArray, fmt.Sprintf("--hosts-entry=%s=%s", IP, name))
+			depHosts = append(depHosts, types.HostsEntry{IP: IP, Name: name})
 		}
 	}
 
-	// create the hostname
-	hostnameLine := fmt.Sprintf("--hostname=%s", container.Name)
-
-	// combine our command parts
-	command = append(command, container.Image)
-	command = append(command, hostnameLine)
-	command = append(command, envArray...)
-	command = append(command, mountArray...)
-	command = append(command, hostsArray...)
-	command = append(command, appNameLine)
-	command = append(command, execArray...)
-
-	return command, nil
+	return types.PodSpec{
+		ProjectName:     projectName,
+		Name:            container.Name,
+		AppName:         appName,
+		ConfigPath:      configPath,
+		Image:           container.Image,
+		Exec:            execArray,
+		Environment:     environment,
+		Mounts:          container.Mounts,
+		Volumes:         volumes,
+		Ports:           container.Ports,
+		HostsEntries:    hostsEntries,
+		DependencyHosts: depHosts,
+	}, nil
 }
 
 // getDependencyChainIPs will return a map of container name=>IP of each dependency of the container and each of their dependencies
-func (container *Container) GetDepChainIPs(projectName string, runningPods rkt.Pods, logger *log.Logger) (map[string][]string, error) {
+func (container *Container) GetDepChainIPs(rt runtime.Runtime, projectName string, runningPods types.Pods, logger *log.Logger) (map[string][]string, error) {
 	// store our ips and names
 	depIPMap := make(map[string][]string)
 	// run through the dependencies
@@ -359,7 +446,7 @@ func (container *Container) GetDepChainIPs(projectName string, runningPods rkt.P
 		// create our ip array
 		depIPMap[name] = make([]string, 0)
 		// get the appName for this depend
-		depAppName, err := rkt.GetAppName(projectName, name)
+		depAppName, err := rt.GetAppName(projectName, name)
 		if err != nil {
 			return depIPMap, err
 		}
@@ -371,7 +458,7 @@ func (container *Container) GetDepChainIPs(projectName string, runningPods rkt.P
 			}
 		} else {
 			// if there is not, check the pod to make sure that it was allowed to exit
-			if container.DependsOn[name].StateConditions.Exit != nil && container.DependsOn[name].StateConditions.Exit.Status == "success" {
+			if container.DependsOn[name].Transient() {
 				logger.Printf("Required dependency %s is not running.  Looks like it is allowed to exit so we are ignoring. \n", name)
 			} else {
 				logger.Printf("Required dependency %s is not running.  No valid exit state.  Failing. \n", name)
@@ -379,7 +466,7 @@ func (container *Container) GetDepChainIPs(projectName string, runningPods rkt.P
 			}
 		}
 		// run on each dependency so we get a full set of heirachical IPs
-		depDepIPMap, err := depContainer.GetDepChainIPs(projectName, runningPods, logger)
+		depDepIPMap, err := depContainer.GetDepChainIPs(rt, projectName, runningPods, logger)
 		if err != nil {
 			return depIPMap, err
 		}
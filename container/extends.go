@@ -0,0 +1,34 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Extends names the container definition that a container is based on
+type Extends struct {
+	// Container is the name of the container to extend
+	Container string `json:"container"`
+	// File is the config file that Container is defined in.  Containers in the same config are used if it is empty
+	File string `json:"file"`
+}
+
+// UnmarshalJSON lets extends be given as just the name of a container, as well as a container and a file
+func (extends *Extends) UnmarshalJSON(b []byte) error {
+	var name string
+	if json.Unmarshal(b, &name) == nil {
+		extends.Container = name
+	} else {
+		type TempExtends Extends
+		var tempExtends TempExtends
+		err := json.Unmarshal(b, &tempExtends)
+		if err != nil {
+			return err
+		}
+		*extends = Extends(tempExtends)
+	}
+	if extends.Container == "" {
+		return errors.New("extends requires the name of a container")
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dansteen/constellation/state"
+)
+
+// LivenessActions are the values accepted by the action of a liveness block
+var LivenessActions = []string{"report", "stop", "fail"}
+
+// livenessInterval is the shortest time we leave between rounds of liveness conditions, so that conditions that succeed
+// straight away do not keep us busy
+const livenessInterval = time.Second
+
+// Liveness holds state conditions that keep being checked once a container has started, for as long as we run in the
+// foreground.  They are checked in rounds: each round lasts until one of the conditions triggers, a round that succeeds
+// starts the next round, and a round that fails means the container is no longer live.
+type Liveness struct {
+	state.StateConditions
+	// Action is what to do once the container is no longer live.  One of LivenessActions
+	Action string `json:"action"`
+}
+
+// conditionBlocks returns our state conditions along with our liveness conditions if we have any
+func (container *Container) conditionBlocks() []*state.StateConditions {
+	blocks := []*state.StateConditions{&container.StateConditions}
+	if container.Liveness != nil {
+		blocks = append(blocks, &container.Liveness.StateConditions)
+	}
+	return blocks
+}
+
+// validate will make sure our liveness block makes sense
+func (liveness *Liveness) Validate() error {
+	if liveness.Action == "" {
+		liveness.Action = "report"
+	}
+	for _, action := range LivenessActions {
+		if liveness.Action == action {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("Invalid liveness action %s.  Must be one of %v", liveness.Action, LivenessActions))
+}
+
+// Monitor will check our liveness conditions until stop is closed, or until a round of them fails.  In that case the
+// failure is returned.  Monitor must be called after Run.  Containers with a restart policy are checked for exits even
+// if they have no liveness conditions, otherwise Monitor returns straight away.
+func (container *Container) Monitor(stop <-chan bool) error {
+	ctx := container.conditions
+	liveness := container.Liveness
+	if liveness == nil && container.Restart != nil && container.Restart.Policy != "never" {
+		liveness = &Liveness{Action: "report"}
+	}
+	if liveness == nil || ctx == nil {
+		return nil
+	}
+	if ctx.waiter == nil {
+		ctx.logger.Printf("Not started by this run.  Output and exit liveness conditions will not trigger")
+	}
+
+	// just like during startup, we are not expected to exit unless our liveness conditions say so.  this is checked
+	// for the whole time we are monitoring rather than each round
+	monitorStop := make(chan bool)
+	defer close(monitorStop)
+	exited := make(chan error, 1)
+	if !liveness.HasExit() && ctx.waiter != nil {
+		exitHandler := state.ExitCondition{
+			Codes:  []int{-1},
+			Status: "success",
+		}
+		go exitHandler.Handle(ctx.waiter, exited, monitorStop, ctx.logger)
+	}
+
+	for {
+		roundStart := time.Now()
+		status := make(chan error, liveness.Count())
+		roundStop := make(chan bool)
+		// an empty liveness block just checks that we do not exit
+		if liveness.Count() > 0 {
+			container.startConditions(&liveness.StateConditions, ctx, status, roundStop)
+		}
+
+		select {
+		case err := <-status:
+			close(roundStop)
+			if err != nil {
+				ctx.logger.Printf("No longer live: %s", err)
+				return err
+			}
+		case err := <-exited:
+			close(roundStop)
+			ctx.logger.Printf("No longer live: %s", err)
+			return err
+		case <-stop:
+			close(roundStop)
+			return nil
+		}
+
+		// once we have exited (and our exit conditions are happy with that) there is nothing left to check
+		if ctx.waiter != nil {
+			select {
+			case <-ctx.waiter.Done():
+				if !liveness.HasExit() {
+					err := <-exited
+					ctx.logger.Printf("No longer live: %s", err)
+					return err
+				}
+				ctx.logger.Printf("Exited.  Stopping liveness checks")
+				return nil
+			default:
+			}
+		}
+
+		// wait before our next round
+		select {
+		case <-time.After(livenessInterval - time.Since(roundStart)):
+		case <-stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package container
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLivenessValidate(t *testing.T) {
+	tests := []struct {
+		action   string
+		expected string
+		err      string
+	}{
+		{action: "", expected: "report"},
+		{action: "report", expected: "report"},
+		{action: "stop", expected: "stop"},
+		{action: "fail", expected: "fail"},
+		{action: "restart", err: "Invalid liveness action restart.  Must be one of [report stop fail]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.action, func(t *testing.T) {
+			liveness := Liveness{Action: test.action}
+			err := liveness.Validate()
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if liveness.Action != test.expected {
+				t.Errorf("action is %s, expected %s", liveness.Action, test.expected)
+			}
+		})
+	}
+}
+
+// monitorTestContainer creates a container with liveness that has been run.  The returned function feeds lines to its
+// output conditions the way its STDOUT would
+func monitorTestContainer(t *testing.T, liveness string) (*Container, func(lines ...string)) {
+	container := &Container{Name: "test"}
+	if liveness != "" {
+		container.Liveness = &Liveness{}
+		err := json.Unmarshal([]byte(liveness), container.Liveness)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	container.conditions = &conditionContext{logger: logger}
+	output := func(lines ...string) {
+		// wait for our liveness conditions to be watching before we hand them anything
+		for start := time.Now(); len(container.conditions.watchers("STDOUT")) == 0; time.Sleep(10 * time.Millisecond) {
+			if time.Since(start) > 5*time.Second {
+				t.Fatal("no output conditions were started")
+			}
+		}
+		scanner := bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))
+		container.handleOutput(scanner, "STDOUT", container.conditions, logger)
+	}
+	return container, output
+}
+
+// monitor runs Monitor in the background and returns where its result will be sent
+func monitor(container *Container, stop <-chan bool) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- container.Monitor(stop)
+	}()
+	return result
+}
+
+func TestMonitor(t *testing.T) {
+	tests := []struct {
+		name     string
+		liveness string
+		lines    []string
+		err      string
+	}{
+		{
+			name:     "failure",
+			liveness: `{"output": [{"source": "STDOUT", "regex": "FATAL", "status": "failure"}]}`,
+			lines:    []string{"serving", "FATAL: out of memory"},
+			err:      "STDOUT matched FATAL. Specified as failure",
+		},
+		{
+			name: "failure in a later round",
+			liveness: `{"output": [{"source": "STDOUT", "regex": "heartbeat", "status": "success"},
+				{"source": "STDOUT", "regex": "FATAL", "status": "failure"}]}`,
+			lines: []string{"heartbeat", "FATAL: out of memory"},
+			err:   "STDOUT matched FATAL. Specified as failure",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			container, output := monitorTestContainer(t, test.liveness)
+			stop := make(chan bool)
+			defer close(stop)
+			result := monitor(container, stop)
+			for index, line := range test.lines {
+				// each line after the first is for the next round
+				if index > 0 {
+					time.Sleep(livenessInterval + 200*time.Millisecond)
+				}
+				output(line)
+			}
+			select {
+			case err := <-result:
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("expected an error containing %q, got %v", test.err, err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Monitor did not return")
+			}
+		})
+	}
+}
+
+func TestMonitorStop(t *testing.T) {
+	container, _ := monitorTestContainer(t, `{"output": [{"source": "STDOUT", "regex": "FATAL", "status": "failure"}]}`)
+	stop := make(chan bool)
+	result := monitor(container, stop)
+	close(stop)
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("a stopped Monitor returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Monitor did not stop")
+	}
+}
+
+func TestMonitorNothingToCheck(t *testing.T) {
+	container, _ := monitorTestContainer(t, "")
+	// a restart policy that never restarts has nothing to watch for either
+	container.Restart = &RestartPolicy{Policy: "never"}
+	select {
+	case err := <-monitor(container, make(chan bool)):
+		if err != nil {
+			t.Errorf("Monitor returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Monitor did not return straight away")
+	}
+}
@@ -0,0 +1,90 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dansteen/constellation/util"
+)
+
+const (
+	// LogFileMaxSize is the size a container log file can grow to before it is rotated
+	LogFileMaxSize = 10 * 1024 * 1024
+	// LogFileMaxFiles is the number of rotated log files that are kept for each container
+	LogFileMaxFiles = 5
+)
+
+// SetLogFile will work out where the output of this container is saved.  If log_file is not set in the config the output
+// is saved to <logDir>/<name>.log, or not at all if logDir is empty.  Relative log_file paths are relative to logDir if it
+// is set and to the project folder at configPath otherwise.
+func (container *Container) SetLogFile(logDir string, configPath string) {
+	if container.LogFile == "" {
+		if logDir != "" {
+			container.LogFile = path.Join(logDir, fmt.Sprintf("%s.log", container.Name))
+		}
+		return
+	}
+	if !path.IsAbs(container.LogFile) {
+		base := configPath
+		if logDir != "" {
+			base = logDir
+		}
+		container.LogFile = path.Join(base, container.LogFile)
+	}
+}
+
+// openLogFile will open the log file for this container if it has one and it is not already open
+func (container *Container) openLogFile() error {
+	container.logLock.Lock()
+	defer container.logLock.Unlock()
+	if container.LogFile == "" || container.logFile != nil {
+		return nil
+	}
+	logFile, err := util.OpenRotatingFile(container.LogFile, LogFileMaxSize, LogFileMaxFiles)
+	if err != nil {
+		return err
+	}
+	container.logFile = logFile
+	return nil
+}
+
+// writeLog will save a line of output from source (STDOUT or STDERR) into our log file if we have one
+func (container *Container) writeLog(source string, line string) {
+	container.logLock.Lock()
+	defer container.logLock.Unlock()
+	if container.logFile == nil {
+		return
+	}
+	fmt.Fprintf(container.logFile, "%s %s %s\n", time.Now().UTC().Format(time.RFC3339Nano), source, line)
+}
+
+// CloseLogFile will close the log file of this container if it is open.  Output after this is no longer saved.
+func (container *Container) CloseLogFile() error {
+	container.logLock.Lock()
+	defer container.logLock.Unlock()
+	if container.logFile == nil {
+		return nil
+	}
+	err := container.logFile.Close()
+	container.logFile = nil
+	return err
+}
+
+// ParseLogLine will split a line from a container log file into the time it was logged, its source, and the line itself
+func ParseLogLine(line string) (time.Time, string, string, error) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return time.Time{}, "", "", errors.New(fmt.Sprintf("Invalid log line: %s", line))
+	}
+	logged, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return logged, "", "", err
+	}
+	if len(parts) == 2 {
+		return logged, parts[1], "", nil
+	}
+	return logged, parts[1], parts[2], nil
+}
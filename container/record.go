@@ -0,0 +1,72 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/dansteen/constellation/types"
+)
+
+// Record holds what we know about the last run of a container that is not available from the runtime.  Records are saved
+// into the project folder so that later invocations (e.g. status) can report on containers started by an earlier run.
+type Record struct {
+	Name    string        `json:"name"`
+	AppName string        `json:"app_name"`
+	Ports   []*types.Port `json:"ports"`
+	// Result is one of "starting", "success" or "failure"
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// Captures are the named capture groups matched by the output and filemonitor conditions of the container
+	Captures map[string]string `json:"captures,omitempty"`
+}
+
+// SaveRecord will save a record of this container into the project folder at configPath
+func (container *Container) SaveRecord(configPath string, appName string, result error) error {
+	record := Record{
+		Name:     container.Name,
+		AppName:  appName,
+		Ports:    container.Ports,
+		Result:   "success",
+		Captures: container.Captures(),
+	}
+	if result != nil {
+		record.Result = "failure"
+		record.Error = result.Error()
+	}
+	return record.save(configPath)
+}
+
+// LoadRecord will load the record of a container from the project folder at configPath.  If the container has never been
+// run an empty record is returned.
+func LoadRecord(configPath string, name string) (Record, error) {
+	record := Record{}
+	data, err := ioutil.ReadFile(recordPath(configPath, name))
+	if os.IsNotExist(err) {
+		return record, nil
+	} else if err != nil {
+		return record, err
+	}
+	err = json.Unmarshal(data, &record)
+	return record, err
+}
+
+// save will write the record out to the project folder
+func (record Record) save(configPath string) error {
+	err := os.MkdirAll(path.Join(configPath, "containers"), 0755)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(recordPath(configPath, record.Name), data, 0644)
+}
+
+// recordPath generates the path to the record of a container
+func recordPath(configPath string, name string) string {
+	return path.Join(configPath, "containers", fmt.Sprintf("%s.json", name))
+}
@@ -0,0 +1,117 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/types"
+)
+
+// RestartPolicies are the values accepted by the policy of a restart block
+var RestartPolicies = []string{"never", "on-failure", "always"}
+
+// RestartPolicy describes when a container should be run again
+type RestartPolicy struct {
+	// Policy is one of RestartPolicies
+	Policy string `json:"policy"`
+	// MaxAttempts is the most times we will restart the container.  It is nil if it was not set, so that 0 can turn
+	// restarts off
+	MaxAttempts *int `json:"max_attempts"`
+	// Backoff is the number of seconds to wait before the first restart.  It doubles with each restart
+	Backoff int `json:"backoff"`
+}
+
+// Validate will make sure our restart policy makes sense, and fill in the defaults
+func (policy *RestartPolicy) Validate() error {
+	if policy.Policy == "" {
+		policy.Policy = "never"
+	}
+	if policy.MaxAttempts == nil {
+		maxAttempts := 3
+		policy.MaxAttempts = &maxAttempts
+	}
+	if policy.Backoff == 0 {
+		policy.Backoff = 1
+	}
+	if *policy.MaxAttempts < 0 || policy.Backoff < 0 {
+		return errors.New("Restart max_attempts and backoff must be positive numbers")
+	}
+	for _, item := range RestartPolicies {
+		if policy.Policy == item {
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("Invalid restart policy %s.  Must be one of %v", policy.Policy, RestartPolicies))
+}
+
+// backoff returns how long to wait before restart number attempt (counting from 1)
+func (policy *RestartPolicy) backoff(attempt int) time.Duration {
+	return time.Duration(policy.Backoff<<uint(attempt-1)) * time.Second
+}
+
+// canRestart checks if our restart policy lets us be restarted again
+func (container *Container) canRestart() bool {
+	return container.Restart != nil && container.Restart.Policy != "never" && container.restarts < *container.Restart.MaxAttempts
+}
+
+// Recover will restart a container that stopped being live after it started, if its restart policy allows it.  reason
+// is why it is no longer live.  It returns whether a restart was attempted, and the result of it.
+func (container *Container) Recover(rt runtime.Runtime, configPath string, projectName string, volumes map[string]types.Volume, hostsEntries []types.HostsEntry, reason error) (bool, error) {
+	if !container.canRestart() {
+		return false, reason
+	}
+	// on-failure leaves containers that exited cleanly alone
+	ctx := container.conditions
+	if container.Restart.Policy == "on-failure" && ctx != nil && ctx.waiter != nil {
+		select {
+		case <-ctx.waiter.Done():
+			if ctx.waiter.ExitCode() == 0 {
+				return false, reason
+			}
+		default:
+		}
+	}
+
+	err := container.restart(rt, configPath, projectName, volumes, hostsEntries, reason)
+	for err != nil && container.canRestart() {
+		err = container.restart(rt, configPath, projectName, volumes, hostsEntries, err)
+	}
+	return true, err
+}
+
+// restart will get rid of our current pod and run a new one in its place once our backoff is up.  reason is why we are
+// being restarted.
+func (container *Container) restart(rt runtime.Runtime, configPath string, projectName string, volumes map[string]types.Volume, hostsEntries []types.HostsEntry, reason error) error {
+	container.restarts++
+	backoff := container.Restart.backoff(container.restarts)
+	container.logger.Printf("Restarting in %s (attempt %d of %d): %s", backoff, container.restarts, *container.Restart.MaxAttempts, reason)
+
+	// stop our pod if it is still running, otherwise we would just pick it up again
+	name, err := rt.GetAppName(projectName, container.Name)
+	if err != nil {
+		return err
+	}
+	runningPods, err := rt.GetRunningPods(projectName)
+	if err != nil {
+		return err
+	}
+	if pod, ok := runningPods.Pods[name]; ok {
+		err = rt.Stop(pod)
+		if err != nil {
+			return err
+		}
+	}
+	// and wait for it to go away if we started it
+	if container.conditions != nil && container.conditions.waiter != nil {
+		select {
+		case <-container.conditions.waiter.Done():
+		case <-time.After(30 * time.Second):
+			container.logger.Printf("Timed out waiting for the old pod to exit")
+		}
+	}
+
+	time.Sleep(backoff)
+	return container.run(rt, configPath, projectName, volumes, hostsEntries)
+}
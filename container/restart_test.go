@@ -0,0 +1,118 @@
+package container
+
+import (
+	"testing"
+	"time"
+)
+
+// intPointer returns a pointer to value, for optional settings
+func intPointer(value int) *int {
+	return &value
+}
+
+func TestRestartPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RestartPolicy
+		expected RestartPolicy
+		err      string
+	}{
+		{
+			name:     "defaults",
+			policy:   RestartPolicy{},
+			expected: RestartPolicy{Policy: "never", MaxAttempts: intPointer(3), Backoff: 1},
+		},
+		{
+			name:     "everything set",
+			policy:   RestartPolicy{Policy: "always", MaxAttempts: intPointer(5), Backoff: 2},
+			expected: RestartPolicy{Policy: "always", MaxAttempts: intPointer(5), Backoff: 2},
+		},
+		{
+			name:     "no attempts",
+			policy:   RestartPolicy{Policy: "on-failure", MaxAttempts: intPointer(0)},
+			expected: RestartPolicy{Policy: "on-failure", MaxAttempts: intPointer(0), Backoff: 1},
+		},
+		{
+			name:   "negative attempts",
+			policy: RestartPolicy{Policy: "on-failure", MaxAttempts: intPointer(-1)},
+			err:    "Restart max_attempts and backoff must be positive numbers",
+		},
+		{
+			name:   "negative backoff",
+			policy: RestartPolicy{Policy: "on-failure", Backoff: -2},
+			err:    "Restart max_attempts and backoff must be positive numbers",
+		},
+		{
+			name:   "unknown policy",
+			policy: RestartPolicy{Policy: "sometimes"},
+			err:    "Invalid restart policy sometimes.  Must be one of [never on-failure always]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := test.policy
+			err := policy.Validate()
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if policy.Policy != test.expected.Policy || *policy.MaxAttempts != *test.expected.MaxAttempts ||
+				policy.Backoff != test.expected.Backoff {
+				t.Errorf("validated to %s/%d/%d, expected %s/%d/%d", policy.Policy, *policy.MaxAttempts, policy.Backoff,
+					test.expected.Policy, *test.expected.MaxAttempts, test.expected.Backoff)
+			}
+		})
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	tests := []struct {
+		backoff  int
+		attempt  int
+		expected time.Duration
+	}{
+		{backoff: 1, attempt: 1, expected: time.Second},
+		{backoff: 1, attempt: 2, expected: 2 * time.Second},
+		{backoff: 1, attempt: 4, expected: 8 * time.Second},
+		{backoff: 5, attempt: 1, expected: 5 * time.Second},
+		{backoff: 5, attempt: 3, expected: 20 * time.Second},
+	}
+
+	for _, test := range tests {
+		policy := RestartPolicy{Backoff: test.backoff}
+		if backoff := policy.backoff(test.attempt); backoff != test.expected {
+			t.Errorf("a backoff of %d waits %s before attempt %d, expected %s", test.backoff, backoff, test.attempt, test.expected)
+		}
+	}
+}
+
+func TestCanRestart(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *RestartPolicy
+		restarts int
+		expected bool
+	}{
+		{name: "no policy"},
+		{name: "never", policy: &RestartPolicy{Policy: "never", MaxAttempts: intPointer(3)}},
+		{name: "first attempt", policy: &RestartPolicy{Policy: "on-failure", MaxAttempts: intPointer(3)}, expected: true},
+		{name: "last attempt", policy: &RestartPolicy{Policy: "always", MaxAttempts: intPointer(3)}, restarts: 2, expected: true},
+		{name: "out of attempts", policy: &RestartPolicy{Policy: "always", MaxAttempts: intPointer(3)}, restarts: 3},
+		{name: "no attempts", policy: &RestartPolicy{Policy: "on-failure", MaxAttempts: intPointer(0)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			container := &Container{Name: "test", Restart: test.policy, restarts: test.restarts}
+			if container.canRestart() != test.expected {
+				t.Errorf("canRestart() is %t, expected %t", container.canRestart(), test.expected)
+			}
+		})
+	}
+}
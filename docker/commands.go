@@ -0,0 +1,206 @@
+// docker implements the constellation runtime on top of the docker (or podman) binary
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	rt "github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/types"
+)
+
+const (
+	// projectLabel is the label we tag each of our containers with so we can find the containers of a project
+	projectLabel = "constellation.project"
+	// appLabel is the label that stores the appName of a container
+	appLabel = "constellation.app"
+)
+
+// Runtime runs constellation pods as docker containers.  Podman accepts the same command line so it is supported as well by
+// setting Binary to "podman"
+type Runtime struct {
+	Binary string
+}
+
+// inspectContainer holds the parts of the output of `docker inspect` that we care about
+type inspectContainer struct {
+	ID    string `json:"Id"`
+	State struct {
+		Status    string    `json:"Status"`
+		StartedAt time.Time `json:"StartedAt"`
+	} `json:"State"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress   string `json:"IPAddress"`
+			IPPrefixLen int    `json:"IPPrefixLen"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// inspectImage holds the parts of the output of `docker image inspect` that we care about
+type inspectImage struct {
+	ID     string `json:"Id"`
+	Config struct {
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	} `json:"Config"`
+}
+
+// Name returns the name of this runtime
+func (runtime *Runtime) Name() string {
+	return runtime.Binary
+}
+
+// GetAllPods will return a list of all the containers that belong to a project
+func (runtime *Runtime) GetAllPods(projectName string) (types.Pods, error) {
+	// hold our pods for this project
+	ourPods := make(map[string]types.Pod)
+
+	// get the ids of all the containers for this project
+	output, err := exec.Command(runtime.Binary, "ps", "--all", "--quiet", "--filter", fmt.Sprintf("label=%s=%s", projectLabel, projectName)).Output()
+	if err != nil {
+		return types.Pods{}, err
+	}
+	ids := strings.Fields(string(output))
+	if len(ids) == 0 {
+		return types.Pods{Pods: ourPods}, nil
+	}
+
+	// and then grab the details of each of them
+	output, err = exec.Command(runtime.Binary, append([]string{"inspect"}, ids...)...).Output()
+	if err != nil {
+		return types.Pods{}, err
+	}
+	containers := make([]inspectContainer, 0)
+	err = json.Unmarshal(output, &containers)
+	if err != nil {
+		return types.Pods{}, err
+	}
+
+	for _, container := range containers {
+		appName := container.Config.Labels[appLabel]
+		pod := types.Pod{
+			Name:      container.ID,
+			State:     container.State.Status,
+			AppNames:  []string{appName},
+			StartedAt: container.State.StartedAt,
+			Networks:  make([]types.Network, 0),
+		}
+		for netName, network := range container.NetworkSettings.Networks {
+			pod.Networks = append(pod.Networks, types.Network{
+				NetName: netName,
+				IP:      network.IPAddress,
+				Mask:    strconv.Itoa(network.IPPrefixLen),
+			})
+		}
+		ourPods[appName] = pod
+	}
+	return types.Pods{Pods: ourPods}, nil
+}
+
+// GetRunningPods will get a list of running containers that are relevant to the provided project, and will return their
+// information indexed by the appName
+func (runtime *Runtime) GetRunningPods(projectName string) (types.Pods, error) {
+	pods, err := runtime.GetAllPods(projectName)
+	if err != nil {
+		return types.Pods{}, err
+	}
+	return rt.RunningPods(pods), nil
+}
+
+// GetAppName will generate the container name for this container/project combination.  Every runtime uses the same naming
+// so that names are consistent between runtimes.
+func (runtime *Runtime) GetAppName(projectName string, containerName string) (string, error) {
+	return rt.AppName(projectName, containerName), nil
+}
+
+// Fetch will pull an image and return its id
+func (runtime *Runtime) Fetch(image string) (string, error) {
+	image = imageName(image)
+	log.Printf("Fetching image: %s", image)
+	output, err := exec.Command(runtime.Binary, "pull", image).CombinedOutput()
+	// if there is an error, print the output
+	if err != nil {
+		log.Printf("%s", output)
+		return "", err
+	}
+	output, err = exec.Command(runtime.Binary, "image", "inspect", "--format", "{{.Id}}", image).Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// GetImageManifest will build a manifest for an image that has been pulled.  Docker images do not have named ports, so
+// each exposed port is named <port>-<protocol> which is the same naming rkt uses when it converts docker images.
+func (runtime *Runtime) GetImageManifest(image string) (*types.ImageManifest, error) {
+	imageManifest := types.ImageManifest{}
+	output, err := exec.Command(runtime.Binary, "image", "inspect", image).Output()
+	if err != nil {
+		log.Printf("%s", output)
+		return &imageManifest, err
+	}
+	images := make([]inspectImage, 0)
+	err = json.Unmarshal(output, &images)
+	if err != nil {
+		return &imageManifest, err
+	}
+	if len(images) == 0 {
+		return &imageManifest, errors.New(fmt.Sprintf("Image %s not found", image))
+	}
+
+	imageManifest.Name = image
+	imageManifest.App.Ports = make([]types.ImageAppPort, 0)
+	for exposed, _ := range images[0].Config.ExposedPorts {
+		// ports are in <port>/<protocol> format
+		parts := strings.SplitN(exposed, "/", 2)
+		port, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return &imageManifest, err
+		}
+		protocol := "tcp"
+		if len(parts) == 2 {
+			protocol = parts[1]
+		}
+		imageManifest.App.Ports = append(imageManifest.App.Ports, types.ImageAppPort{
+			Name:     fmt.Sprintf("%d-%s", port, protocol),
+			Protocol: protocol,
+			Port:     port,
+		})
+	}
+	// keep our ports in a stable order
+	sort.Slice(imageManifest.App.Ports, func(i, j int) bool {
+		return imageManifest.App.Ports[i].Name < imageManifest.App.Ports[j].Name
+	})
+	return &imageManifest, nil
+}
+
+// Stop will stop a running container
+func (runtime *Runtime) Stop(pod types.Pod) error {
+	return runtime.runLogged("stop", pod.Name)
+}
+
+// Remove will remove a container.  The container must already be stopped
+func (runtime *Runtime) Remove(pod types.Pod) error {
+	return runtime.runLogged("rm", pod.Name)
+}
+
+// runLogged will run a command to completion and log the command and its output
+func (runtime *Runtime) runLogged(args ...string) error {
+	command := append([]string{runtime.Binary}, args...)
+	log.Printf("Running: %+v", command)
+	output, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+	log.Printf("%s", output)
+	return err
+}
+
+// imageName will strip the rkt style docker:// prefix from an image name since docker does not understand it
+func imageName(image string) string {
+	return strings.TrimPrefix(image, "docker://")
+}
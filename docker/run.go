@@ -0,0 +1,139 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/dansteen/constellation/types"
+)
+
+// RunCommand will generate the docker command that runs the container described by spec.  The command is not started.
+func (runtime *Runtime) RunCommand(spec types.PodSpec) (*exec.Cmd, error) {
+	// docker will refuse to start a container if one of the same name already exists, so we clear out any container left
+	// over from a previous run of this project.  Running containers are never passed in here.
+	pods, err := runtime.GetAllPods(spec.ProjectName)
+	if err != nil {
+		return nil, err
+	}
+	if pod, ok := pods.Pods[spec.AppName]; ok {
+		err = runtime.Remove(pod)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	commandLine := []string{
+		runtime.Binary, "run",
+		fmt.Sprintf("--name=%s", spec.AppName),
+		fmt.Sprintf("--hostname=%s", spec.Name),
+		fmt.Sprintf("--network=%s", networkName(spec.ProjectName)),
+		fmt.Sprintf("--label=%s=%s", projectLabel, spec.ProjectName),
+		fmt.Sprintf("--label=%s=%s", appLabel, spec.AppName),
+	}
+
+	// environment
+	for varName, varValue := range spec.Environment {
+		commandLine = append(commandLine, fmt.Sprintf("--env=%s=%s", varName, varValue))
+	}
+
+	// mounts.  docker has no separate notion of volumes so we resolve each mount against its volume here
+	for _, mount := range spec.Mounts {
+		volume, ok := spec.Volumes[mount.Volume]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Mount in %s referenced volume %s which is not defined", spec.Name, mount.Volume))
+		}
+		switch volume.Kind {
+		case "host":
+			commandLine = append(commandLine, fmt.Sprintf("--volume=%s:%s", volume.Path, mount.Path))
+		case "empty":
+			commandLine = append(commandLine, fmt.Sprintf("--volume=%s", mount.Path))
+		default:
+			return nil, errors.New(fmt.Sprintf("Volume %s has unsupported kind %s", volume.Name, volume.Kind))
+		}
+	}
+
+	// port maps
+	for _, port := range spec.Ports {
+		commandLine = append(commandLine, fmt.Sprintf("--publish=%d:%d/%s", port.HostPort, port.Port, port.Protocol))
+	}
+
+	// hosts entries.  dependencies can also be reached by name through the project network, but we add them explicitly
+	// to match the behaviour of rkt
+	for _, entry := range append(spec.HostsEntries, spec.DependencyHosts...) {
+		commandLine = append(commandLine, fmt.Sprintf("--add-host=%s:%s", entry.Name, entry.IP))
+	}
+
+	// exec string.  the first part replaces the entrypoint and the rest are passed as arguments after the image
+	if len(spec.Exec) > 0 {
+		commandLine = append(commandLine, fmt.Sprintf("--entrypoint=%s", spec.Exec[0]))
+	}
+	commandLine = append(commandLine, imageName(spec.Image))
+	if len(spec.Exec) > 1 {
+		commandLine = append(commandLine, spec.Exec[1:]...)
+	}
+
+	return exec.Command(commandLine[0], commandLine[1:]...), nil
+}
+
+// CreateNetwork will create a user-defined bridge network for this project, using the subnet from a fresh network config.
+// If the network already exists from a previous run of the project it is reused.
+func (runtime *Runtime) CreateNetwork(projectName string, configPath string) error {
+	name := networkName(projectName)
+	if runtime.networkExists(name) {
+		log.Printf("Using network from previous project run: %s", name)
+		return nil
+	}
+	network, err := types.NewNetworkConfig(projectName)
+	if err != nil {
+		return err
+	}
+	return runtime.runLogged("network", "create",
+		"--driver=bridge",
+		fmt.Sprintf("--subnet=%s", network.IPAM.Subnet),
+		fmt.Sprintf("--label=%s=%s", projectLabel, projectName),
+		name)
+}
+
+// RemoveNetwork will remove the network for this project if it exists
+func (runtime *Runtime) RemoveNetwork(projectName string, configPath string) error {
+	name := networkName(projectName)
+	if !runtime.networkExists(name) {
+		return nil
+	}
+	return runtime.runLogged("network", "rm", name)
+}
+
+// networkExists checks if a network of the provided name exists
+func (runtime *Runtime) networkExists(name string) bool {
+	return exec.Command(runtime.Binary, "network", "inspect", name).Run() == nil
+}
+
+// networkName will generate the name of the network for a project
+func networkName(projectName string) string {
+	return fmt.Sprintf("constellation-%s", projectName)
+}
+
+// LogsCommand will generate the command that prints the output of a container
+func (runtime *Runtime) LogsCommand(pod types.Pod, appName string, options types.LogOptions) (*exec.Cmd, error) {
+	commandLine := []string{runtime.Binary, "logs", "--timestamps"}
+	if options.Follow {
+		commandLine = append(commandLine, "--follow")
+	}
+	if !options.Since.IsZero() {
+		commandLine = append(commandLine, fmt.Sprintf("--since=%s", options.Since.Format(time.RFC3339)))
+	}
+	if options.Tail >= 0 {
+		commandLine = append(commandLine, fmt.Sprintf("--tail=%d", options.Tail))
+	}
+	commandLine = append(commandLine, pod.Name)
+	return exec.Command(commandLine[0], commandLine[1:]...), nil
+}
+
+// ExecCommand will generate the command that runs a command inside a running container
+func (runtime *Runtime) ExecCommand(pod types.Pod, appName string, command []string) (*exec.Cmd, error) {
+	commandLine := append([]string{runtime.Binary, "exec", pod.Name}, command...)
+	return exec.Command(commandLine[0], commandLine[1:]...), nil
+}
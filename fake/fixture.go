@@ -0,0 +1,55 @@
+package fake
+
+import (
+	"io/ioutil"
+
+	"github.com/dansteen/constellation/types"
+	"github.com/ghodss/yaml"
+)
+
+// Fixture describes how the fake runtime should behave.  It is normally loaded from a yaml file.
+type Fixture struct {
+	// Images holds the manifest of each image indexed by the image name used in the constellation file
+	Images map[string]types.ImageManifest `json:"images"`
+	// Pods holds the behaviour of the pod for each container indexed by container name
+	Pods map[string]PodFixture `json:"pods"`
+}
+
+// PodFixture describes what a simulated pod does once it is started
+type PodFixture struct {
+	// IP is the address the pod is given on the project network
+	IP string `json:"ip"`
+	// Steps are run in order once the pod starts
+	Steps []Step `json:"steps"`
+	// ExitCode is the code the pod exits with after its last step.  If it is not set the pod keeps running until stopped.
+	ExitCode *int `json:"exit_code"`
+}
+
+// Step is a single action taken by a simulated pod.  Only one of the fields should be set
+type Step struct {
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+	// Delay is the number of seconds to wait before moving on to the next step
+	Delay float64 `json:"delay"`
+	// Env will print the value of an environment variable the pod was started with to stdout
+	Env string `json:"env"`
+	// Write will append a line to a file on the host, which is useful for driving filemonitor conditions
+	Write *FileWrite `json:"write"`
+}
+
+// FileWrite appends Line to File
+type FileWrite struct {
+	File string `json:"file"`
+	Line string `json:"line"`
+}
+
+// LoadFixture will load a fixture from a yaml file
+func LoadFixture(path string) (Fixture, error) {
+	fixture := Fixture{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fixture, err
+	}
+	err = yaml.Unmarshal(data, &fixture)
+	return fixture, err
+}
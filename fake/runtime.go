@@ -0,0 +1,369 @@
+// fake implements a constellation runtime that simulates pods from a fixture.  It lets a constellation be exercised
+// end-to-end (dependency ordering, state conditions, port tables) on machines without a container runtime.
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	rt "github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/types"
+)
+
+// Runtime simulates pods.  Each pod is a shell process that plays back the steps in the fixture for its container.  The
+// state of each pod is saved in StateDir so that later invocations (stop, clean, status) can find it.
+type Runtime struct {
+	Fixture  Fixture
+	StateDir string
+
+	lock sync.Mutex
+	// pods holds the pods saved in StateDir as of the last load, indexed by appName
+	pods map[string]*pod
+	// images maps the hashes we hand out from Fetch back to image names
+	images map[string]string
+}
+
+// pod holds the state of a simulated pod
+type pod struct {
+	Name        string `json:"name"`
+	AppName     string `json:"app_name"`
+	ProjectName string `json:"project_name"`
+	// Container is the name of the container the pod was started for, which its fixture is stored under
+	Container string `json:"container"`
+	IP        string `json:"ip"`
+	Stopped   bool   `json:"stopped"`
+	// PID is the process that plays back the pod.  It leads its own process group, which also holds anything it starts.
+	// The process writes it to a file once it is running, so it is 0 until then.
+	PID       int       `json:"-"`
+	StartedAt time.Time `json:"-"`
+}
+
+// New will create a fake runtime driven by the provided fixture that keeps the state of its pods in stateDir
+func New(fixture Fixture, stateDir string) *Runtime {
+	return &Runtime{
+		Fixture:  fixture,
+		StateDir: stateDir,
+		pods:     make(map[string]*pod),
+		images:   make(map[string]string),
+	}
+}
+
+// Name returns the name of this runtime
+func (runtime *Runtime) Name() string {
+	return "fake"
+}
+
+// Fetch will pretend to fetch an image and return a hash for it
+func (runtime *Runtime) Fetch(image string) (string, error) {
+	log.Printf("Fetching image: %s", image)
+	runtime.lock.Lock()
+	defer runtime.lock.Unlock()
+	hash := fmt.Sprintf("sha512-fake%d", len(runtime.images))
+	runtime.images[hash] = image
+	return hash, nil
+}
+
+// GetImageManifest will return the manifest from the fixture for a fetched image
+func (runtime *Runtime) GetImageManifest(image string) (*types.ImageManifest, error) {
+	runtime.lock.Lock()
+	defer runtime.lock.Unlock()
+	name, ok := runtime.images[image]
+	if !ok {
+		return &types.ImageManifest{}, errors.New(fmt.Sprintf("Image %s has not been fetched", image))
+	}
+	manifest := runtime.Fixture.Images[name]
+	manifest.Name = name
+	return &manifest, nil
+}
+
+// GetAllPods will return all of the simulated pods for a project
+func (runtime *Runtime) GetAllPods(projectName string) (types.Pods, error) {
+	runtime.lock.Lock()
+	defer runtime.lock.Unlock()
+	err := runtime.load()
+	if err != nil {
+		return types.Pods{}, err
+	}
+	ourPods := make(map[string]types.Pod)
+	for appName, pod := range runtime.pods {
+		if pod.ProjectName == projectName && pod.PID != 0 {
+			ourPods[appName] = pod.current(runtime.Fixture.Pods[pod.Container])
+		}
+	}
+	return types.Pods{Pods: ourPods}, nil
+}
+
+// GetRunningPods will return the running simulated pods for a project
+func (runtime *Runtime) GetRunningPods(projectName string) (types.Pods, error) {
+	pods, err := runtime.GetAllPods(projectName)
+	if err != nil {
+		return types.Pods{}, err
+	}
+	return rt.RunningPods(pods), nil
+}
+
+// GetAppName will generate the app name for this container/project combination in the same way every other runtime does
+func (runtime *Runtime) GetAppName(projectName string, containerName string) (string, error) {
+	return rt.AppName(projectName, containerName), nil
+}
+
+// RunCommand will register a simulated pod and return a shell command that plays back its steps.  The pod is not
+// listed until the command has been started.
+func (runtime *Runtime) RunCommand(spec types.PodSpec) (*exec.Cmd, error) {
+	podFixture, ok := runtime.Fixture.Pods[spec.Name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No fixture defined for %s", spec.Name))
+	}
+	// the pod records its own pid as soon as it is running
+	script := fmt.Sprintf("echo $$ > %s\n%s", quote(runtime.pidPath(spec.AppName)), podFixture.script())
+	command := exec.Command("/bin/sh", "-c", script)
+	// the environment of the pod is what its env steps print
+	command.Env = os.Environ()
+	for name, value := range spec.Environment {
+		command.Env = append(command.Env, fmt.Sprintf("%s=%s", name, value))
+	}
+
+	runtime.lock.Lock()
+	defer runtime.lock.Unlock()
+	newPod := &pod{
+		Name:        fmt.Sprintf("fake-%s", spec.AppName),
+		AppName:     spec.AppName,
+		ProjectName: spec.ProjectName,
+		Container:   spec.Name,
+		IP:          podFixture.IP,
+	}
+	// a pod that is run again starts without a pid until its new process is running
+	err := os.Remove(runtime.pidPath(spec.AppName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = runtime.save(newPod)
+	if err != nil {
+		return nil, err
+	}
+	runtime.pods[spec.AppName] = newPod
+	return command, nil
+}
+
+// Stop will kill the process group of a simulated pod
+func (runtime *Runtime) Stop(target types.Pod) error {
+	runtime.lock.Lock()
+	defer runtime.lock.Unlock()
+	err := runtime.load()
+	if err != nil {
+		return err
+	}
+	for _, pod := range runtime.pods {
+		if pod.Name == target.Name {
+			pod.Stopped = true
+			if pod.PID != 0 {
+				err := syscall.Kill(-pod.PID, syscall.SIGKILL)
+				if err != nil && err != syscall.ESRCH {
+					return err
+				}
+			}
+			return runtime.save(pod)
+		}
+	}
+	return errors.New(fmt.Sprintf("No such pod: %s", target.Name))
+}
+
+// Remove will forget a simulated pod
+func (runtime *Runtime) Remove(target types.Pod) error {
+	runtime.lock.Lock()
+	defer runtime.lock.Unlock()
+	err := runtime.load()
+	if err != nil {
+		return err
+	}
+	for appName, pod := range runtime.pods {
+		if pod.Name == target.Name {
+			delete(runtime.pods, appName)
+			for _, path := range []string{runtime.statePath(appName), runtime.pidPath(appName)} {
+				err := os.Remove(path)
+				if err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("No such pod: %s", target.Name))
+}
+
+// LogsCommand will generate a command that prints the output the fixture of a simulated pod produces.  Simulated pods do not
+// keep timestamps so Since is ignored.
+func (runtime *Runtime) LogsCommand(target types.Pod, appName string, options types.LogOptions) (*exec.Cmd, error) {
+	runtime.lock.Lock()
+	defer runtime.lock.Unlock()
+	err := runtime.load()
+	if err != nil {
+		return nil, err
+	}
+	pod, ok := runtime.pods[appName]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("No such pod: %s", target.Name))
+	}
+	lines := make([]string, 0)
+	for _, step := range runtime.Fixture.Pods[pod.Container].Steps {
+		if step.Stdout != "" {
+			lines = append(lines, fmt.Sprintf("echo %s", quote(step.Stdout)))
+		}
+		if step.Stderr != "" {
+			lines = append(lines, fmt.Sprintf("echo %s >&2", quote(step.Stderr)))
+		}
+	}
+	if options.Tail >= 0 && options.Tail < len(lines) {
+		lines = lines[len(lines)-options.Tail:]
+	}
+	return exec.Command("/bin/sh", "-c", strings.Join(lines, "\n")), nil
+}
+
+// ExecCommand will generate a command that runs command on the host, since simulated pods have nothing to run it in
+func (runtime *Runtime) ExecCommand(pod types.Pod, appName string, command []string) (*exec.Cmd, error) {
+	if len(command) == 0 {
+		return nil, errors.New("No command provided")
+	}
+	return exec.Command(command[0], command[1:]...), nil
+}
+
+// CreateNetwork does nothing since simulated pods do not have a network
+func (runtime *Runtime) CreateNetwork(projectName string, configPath string) error {
+	return nil
+}
+
+// RemoveNetwork does nothing since simulated pods do not have a network
+func (runtime *Runtime) RemoveNetwork(projectName string, configPath string) error {
+	return nil
+}
+
+// load will read in the saved state of every pod, along with the pid each one has recorded.  The caller must hold the
+// lock.
+func (runtime *Runtime) load() error {
+	files, err := filepath.Glob(runtime.statePath("*"))
+	if err != nil {
+		return err
+	}
+	runtime.pods = make(map[string]*pod)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		saved := &pod{}
+		err = json.Unmarshal(data, saved)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Could not read pod state from %s: %s", file, err))
+		}
+
+		// the pid file is written by the pod once it has started
+		pidFile := runtime.pidPath(saved.AppName)
+		info, err := os.Stat(pidFile)
+		if err == nil {
+			data, err = ioutil.ReadFile(pidFile)
+			if err != nil {
+				return err
+			}
+			saved.PID, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+			saved.StartedAt = info.ModTime()
+		}
+		runtime.pods[saved.AppName] = saved
+	}
+	return nil
+}
+
+// save will write the state of a pod to disk.  The caller must hold the lock.
+func (runtime *Runtime) save(pod *pod) error {
+	err := os.MkdirAll(runtime.StateDir, 0755)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(pod)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(runtime.statePath(pod.AppName), data, 0644)
+}
+
+// statePath is the file the state of the pod for appName is saved in
+func (runtime *Runtime) statePath(appName string) string {
+	return filepath.Join(runtime.StateDir, fmt.Sprintf("fake-%s.json", appName))
+}
+
+// pidPath is the file the process of the pod for appName writes its pid to
+func (runtime *Runtime) pidPath(appName string) string {
+	return filepath.Join(runtime.StateDir, fmt.Sprintf("fake-%s.pid", appName))
+}
+
+// current returns the pod with its state worked out from its fixture.  A pod that has an exit code is considered to
+// have exited once all of its delays have passed, and any pod has exited once its process group is gone.
+func (pod *pod) current(podFixture PodFixture) types.Pod {
+	current := types.Pod{
+		Name:      pod.Name,
+		State:     "running",
+		AppNames:  []string{pod.AppName},
+		StartedAt: pod.StartedAt,
+		Networks: []types.Network{
+			{NetName: pod.ProjectName, IP: pod.IP},
+		},
+	}
+	if pod.Stopped || (podFixture.ExitCode != nil && time.Since(pod.StartedAt) >= podFixture.duration()) {
+		current.State = "exited"
+	} else if err := syscall.Kill(-pod.PID, 0); err == syscall.ESRCH {
+		current.State = "exited"
+	}
+	return current
+}
+
+// duration is the total amount of time the steps of this pod take
+func (podFixture PodFixture) duration() time.Duration {
+	total := 0.0
+	for _, step := range podFixture.Steps {
+		total += step.Delay
+	}
+	return time.Duration(total * float64(time.Second))
+}
+
+// script generates the shell script that plays back the steps of this pod
+func (podFixture PodFixture) script() string {
+	lines := make([]string, 0)
+	for _, step := range podFixture.Steps {
+		if step.Stdout != "" {
+			lines = append(lines, fmt.Sprintf("echo %s", quote(step.Stdout)))
+		}
+		if step.Stderr != "" {
+			lines = append(lines, fmt.Sprintf("echo %s >&2", quote(step.Stderr)))
+		}
+		if step.Env != "" {
+			lines = append(lines, fmt.Sprintf(`printf '%%s\n' "$%s"`, step.Env))
+		}
+		if step.Write != nil {
+			lines = append(lines, fmt.Sprintf("echo %s >> %s", quote(step.Write.Line), quote(step.Write.File)))
+		}
+		if step.Delay > 0 {
+			lines = append(lines, fmt.Sprintf("sleep %g", step.Delay))
+		}
+	}
+	if podFixture.ExitCode != nil {
+		lines = append(lines, fmt.Sprintf("exit %d", *podFixture.ExitCode))
+	} else {
+		lines = append(lines, "while true; do sleep 1; done")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quote will single quote a string for the shell
+func quote(value string) string {
+	return fmt.Sprintf("'%s'", strings.Replace(value, "'", `'\''`, -1))
+}
@@ -1,3 +1,4 @@
+// rkt implements the constellation runtime on top of the rkt binary
 package rkt
 
 import (
@@ -5,34 +6,38 @@ import (
 	"fmt"
 	"log"
 	"os/exec"
-	"regexp"
 	"strings"
 
+	rt "github.com/dansteen/constellation/runtime"
+	"github.com/dansteen/constellation/types"
 	"gopkg.in/yaml.v2"
 )
 
-// Pods stores a number of pods indexed by their appName
-type Pods struct {
-	Pods map[string]Pod
+// Runtime runs constellation pods using rkt
+type Runtime struct{}
+
+// Name returns the name of this runtime
+func (runtime *Runtime) Name() string {
+	return "rkt"
 }
 
 // GetAllPods will return a list of all pods in rkt
-func GetAllPods(projectName string) (Pods, error) {
+func (runtime *Runtime) GetAllPods(projectName string) (types.Pods, error) {
 	// create a type to hold our pods
-	allPods := make([]Pod, 0)
+	allPods := make([]types.Pod, 0)
 	// hold our running pods for this project
-	ourPods := make(map[string]Pod)
+	ourPods := make(map[string]types.Pod)
 
 	// get all the pods
 	command := strings.Split("rkt list --format=json", " ")
 	listCmd := exec.Command(command[0], command[1:]...)
 	output, err := listCmd.Output()
 	if err != nil {
-		return Pods{}, err
+		return types.Pods{}, err
 	}
 	err = json.Unmarshal(output, &allPods)
 	if err != nil {
-		return Pods{}, err
+		return types.Pods{}, err
 	}
 
 	// filter on the ones for our project
@@ -43,41 +48,26 @@ func GetAllPods(projectName string) (Pods, error) {
 			}
 		}
 	}
-	return Pods{Pods: ourPods}, nil
+	return types.Pods{Pods: ourPods}, nil
 }
 
 // GetRunningPods will get a list of running pods that are relevant to the provided project, and will return their information indexed by
 // the appName
-func GetRunningPods(projectName string) (Pods, error) {
-	// grab our pods
-	pods, err := GetAllPods(projectName)
+func (runtime *Runtime) GetRunningPods(projectName string) (types.Pods, error) {
+	pods, err := runtime.GetAllPods(projectName)
 	if err != nil {
-		return Pods{}, err
-	}
-	// strip out our running pods
-	for name, pod := range pods.Pods {
-		if pod.State != "running" {
-			delete(pods.Pods, name)
-		}
+		return types.Pods{}, err
 	}
-	return pods, nil
+	return rt.RunningPods(pods), nil
 }
 
 // GetAppName will generate the app name for this container/project combination
-func GetAppName(projectName string, containerName string) (string, error) {
-	// generate the appname as a combination of projectName and containername
-	// we also need to strip out any non-alphanumeric characters or rkt will complain
-	reg, err := regexp.Compile("[^A-Za-z0-9]+")
-	if err != nil {
-		return "", err
-	}
-	appName := reg.ReplaceAllString(containerName, "")
-	appName = fmt.Sprintf("%s-%s", projectName, appName)
-	return appName, nil
+func (runtime *Runtime) GetAppName(projectName string, containerName string) (string, error) {
+	return rt.AppName(projectName, containerName), nil
 }
 
 // Fetch will fetch a rkt image and return the image hash
-func Fetch(image string) (string, error) {
+func (runtime *Runtime) Fetch(image string) (string, error) {
 	log.Printf("Fetching image: %s", image)
 	// fetch our pod
 	command := strings.Split(fmt.Sprintf("rkt fetch --insecure-options=all-fetch --trust-keys-from-https=true %s", image), " ")
@@ -92,9 +82,9 @@ func Fetch(image string) (string, error) {
 
 // GetImageManifest will get the manifest for an image that has been fetched
 // it returns the manifest mapped to a generic interface
-func GetImageManifest(image string) (*ImageManifest, error) {
+func (runtime *Runtime) GetImageManifest(image string) (*types.ImageManifest, error) {
 	// setup an object to hold our ImageManifest
-	imageManifest := ImageManifest{}
+	imageManifest := types.ImageManifest{}
 	// grab our manifest in json
 	command := strings.Split(fmt.Sprintf("rkt image cat-manifest %s", image), " ")
 	listCmd := exec.Command(command[0], command[1:]...)
@@ -111,3 +101,24 @@ func GetImageManifest(image string) (*ImageManifest, error) {
 	}
 	return &imageManifest, nil
 }
+
+// Stop will stop a running pod
+func (runtime *Runtime) Stop(pod types.Pod) error {
+	command := strings.Split(fmt.Sprintf("rkt stop --force %s", pod.Name), " ")
+	return runLogged(command)
+}
+
+// Remove will remove a pod.  The pod must already be stopped
+func (runtime *Runtime) Remove(pod types.Pod) error {
+	command := strings.Split(fmt.Sprintf("rkt rm %s", pod.Name), " ")
+	return runLogged(command)
+}
+
+// runLogged will run a command to completion and log the command and its output
+func runLogged(command []string) error {
+	log.Printf("Running: %+v", command)
+	cmd := exec.Command(command[0], command[1:]...)
+	output, err := cmd.CombinedOutput()
+	log.Printf("%s", output)
+	return err
+}
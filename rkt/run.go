@@ -0,0 +1,134 @@
+package rkt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dansteen/constellation/types"
+)
+
+// RunCommand will generate the rkt command that runs the pod described by spec.  The command is not started.
+func (runtime *Runtime) RunCommand(spec types.PodSpec) (*exec.Cmd, error) {
+	// generate the different components
+	commandLine := make([]string, 0)
+
+	// generate environment strings
+	envArray := make([]string, 0)
+	for varName, varValue := range spec.Environment {
+		envArray = append(envArray, fmt.Sprintf("--environment=%s=%s", varName, varValue))
+	}
+
+	// exec string
+	execArray := make([]string, 0)
+	if len(spec.Exec) > 0 {
+		// first prime our array
+		execArray = append(execArray, "--exec")
+		execArray = append(execArray, spec.Exec[0])
+	}
+	// if there is more than one part the rkt command requires that other compoments come after a double hyphen
+	if len(spec.Exec) > 1 {
+		execArray = append(execArray, "--")
+		execArray = append(execArray, spec.Exec[1:]...)
+	}
+
+	// mount strings
+	mountArray := make([]string, 0)
+	for _, mount := range spec.Mounts {
+		mountArray = append(mountArray, "--mount", fmt.Sprintf("volume=%s,target=%s", mount.Volume, mount.Path))
+	}
+
+	// hosts entries for our dependencies
+	hostsArray := make([]string, 0)
+	for _, entry := range spec.DependencyHosts {
+		hostsArray = append(hostsArray, fmt.Sprintf("--hosts-entry=%s=%s", entry.IP, entry.Name))
+	}
+
+	// combine our command parts
+	commandLine = append(commandLine, spec.Image)
+	commandLine = append(commandLine, fmt.Sprintf("--hostname=%s", spec.Name))
+	commandLine = append(commandLine, envArray...)
+	commandLine = append(commandLine, mountArray...)
+	commandLine = append(commandLine, hostsArray...)
+	commandLine = append(commandLine, fmt.Sprintf("--name=%s", spec.AppName))
+	commandLine = append(commandLine, execArray...)
+
+	// prefix volumes
+	for _, volume := range spec.Volumes {
+		commandLine = append([]string{"--volume", fmt.Sprintf("%s,kind=%s,source=%s", volume.Name, volume.Kind, volume.Path)}, commandLine...)
+	}
+
+	// prefix hostsEntries
+	for _, entry := range spec.HostsEntries {
+		commandLine = append([]string{"--hosts-entry", fmt.Sprintf("%s=%s", entry.IP, entry.Name)}, commandLine...)
+	}
+
+	// prefix our port maps
+	for _, port := range spec.Ports {
+		commandLine = append([]string{"--port", fmt.Sprintf("%s:%d", port.Name, port.HostPort)}, commandLine...)
+	}
+
+	// prefix TODO: we want to allow settings for these
+	commandLine = append(strings.Split(fmt.Sprintf("rkt run --local-config=%s --dns=host", spec.ConfigPath), " "), commandLine...)
+
+	return exec.Command(commandLine[0], commandLine[1:]...), nil
+}
+
+// CreateNetwork will write out the rkt network config for this project into configPath.  If a config from a previous run
+// of the project already exists it is reused.
+func (runtime *Runtime) CreateNetwork(projectName string, configPath string) error {
+	// generate our network file
+	netConfigFile := fmt.Sprintf("%s/net.d/%s.conf", configPath, projectName)
+	// first check if we already have a config file for this project
+	if _, err := os.Stat(netConfigFile); !os.IsNotExist(err) {
+		log.Printf("Using config from previous project run: %s", configPath)
+		return nil
+	}
+	network, err := types.NewNetworkConfig(projectName)
+	if err != nil {
+		return err
+	}
+	// convert our config to json
+	networkJSON, err := json.MarshalIndent(network, "", "  ")
+	if err != nil {
+		return err
+	}
+	// make our config folder
+	err = os.MkdirAll(fmt.Sprintf("%s/net.d", configPath), 0755)
+	if err != nil {
+		return err
+	}
+	// write our network files
+	return ioutil.WriteFile(netConfigFile, networkJSON, 0644)
+}
+
+// RemoveNetwork will remove the rkt network config for this project
+func (runtime *Runtime) RemoveNetwork(projectName string, configPath string) error {
+	return os.RemoveAll(fmt.Sprintf("%s/net.d", configPath))
+}
+
+// LogsCommand will generate the command that prints the output of an app in a pod.  rkt pods log to the systemd journal of
+// the host under the machine name rkt-<uuid>
+func (runtime *Runtime) LogsCommand(pod types.Pod, appName string, options types.LogOptions) (*exec.Cmd, error) {
+	commandLine := []string{"journalctl", fmt.Sprintf("--machine=rkt-%s", pod.Name), fmt.Sprintf("--identifier=%s", appName), "--output=short-iso", "--no-pager"}
+	if options.Follow {
+		commandLine = append(commandLine, "--follow")
+	}
+	if !options.Since.IsZero() {
+		commandLine = append(commandLine, fmt.Sprintf("--since=%s", options.Since.Local().Format("2006-01-02 15:04:05")))
+	}
+	if options.Tail >= 0 {
+		commandLine = append(commandLine, fmt.Sprintf("--lines=%d", options.Tail))
+	}
+	return exec.Command(commandLine[0], commandLine[1:]...), nil
+}
+
+// ExecCommand will generate the command that runs a command inside an app of a running pod
+func (runtime *Runtime) ExecCommand(pod types.Pod, appName string, command []string) (*exec.Cmd, error) {
+	commandLine := append([]string{"rkt", "enter", fmt.Sprintf("--app=%s", appName), pod.Name}, command...)
+	return exec.Command(commandLine[0], commandLine[1:]...), nil
+}
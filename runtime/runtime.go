@@ -0,0 +1,64 @@
+// runtime defines the interface constellation uses to talk to a container runtime
+package runtime
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+
+	"github.com/dansteen/constellation/types"
+)
+
+// Runtime is implemented by each of the container runtimes that constellation can drive.  The orchestration logic in
+// the container and cmd packages only ever talks to a Runtime, so it does not need to know about runtime specific flags.
+type Runtime interface {
+	// Name returns the name of the runtime
+	Name() string
+	// Fetch will fetch an image and return an identifier (hash) for it
+	Fetch(image string) (string, error)
+	// GetImageManifest will return the manifest of an image that has already been fetched
+	GetImageManifest(image string) (*types.ImageManifest, error)
+	// GetAllPods will return all of the pods that belong to a project indexed by their appName
+	GetAllPods(projectName string) (types.Pods, error)
+	// GetRunningPods will return the running pods that belong to a project indexed by their appName
+	GetRunningPods(projectName string) (types.Pods, error)
+	// GetAppName will generate the app name for this container/project combination
+	GetAppName(projectName string, containerName string) (string, error)
+	// RunCommand will generate the command that will run the pod described by spec.  The command must not be started since
+	// the caller needs to attach to its outputs first.
+	RunCommand(spec types.PodSpec) (*exec.Cmd, error)
+	// LogsCommand will generate the command that prints the output of the app appName in pod.  The command must not be
+	// started.
+	LogsCommand(pod types.Pod, appName string, options types.LogOptions) (*exec.Cmd, error)
+	// ExecCommand will generate the command that runs command inside the app appName of a running pod.  The command must
+	// not be started.
+	ExecCommand(pod types.Pod, appName string, command []string) (*exec.Cmd, error)
+	// Stop will stop a running pod
+	Stop(pod types.Pod) error
+	// Remove will remove a stopped pod
+	Remove(pod types.Pod) error
+	// CreateNetwork will set up the network that the pods in a project are attached to.  configPath is a folder the
+	// runtime can use to store files for this project.
+	CreateNetwork(projectName string, configPath string) error
+	// RemoveNetwork will tear down the network created by CreateNetwork
+	RemoveNetwork(projectName string, configPath string) error
+}
+
+// appNameCharacters matches everything that can not be in an app name.  rkt only allows alphanumerics
+var appNameCharacters = regexp.MustCompile("[^A-Za-z0-9]+")
+
+// AppName generates the app name for a container/project combination.  Every runtime names apps this way so that names
+// are the same whichever runtime is used.
+func AppName(projectName string, containerName string) string {
+	return fmt.Sprintf("%s-%s", projectName, appNameCharacters.ReplaceAllString(containerName, ""))
+}
+
+// RunningPods strips everything but the running pods out of pods
+func RunningPods(pods types.Pods) types.Pods {
+	for name, pod := range pods.Pods {
+		if pod.State != "running" {
+			delete(pods.Pods, name)
+		}
+	}
+	return pods
+}
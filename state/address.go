@@ -0,0 +1,27 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AddressResolver returns the address (host:port) to use to reach the port named port in the image manifest of a
+// container.  via is either "pod" to connect to the pod IP directly or "host" to connect through the port mapped onto the
+// host machine.
+type AddressResolver func(port string, via string) (string, error)
+
+// Vias are the values accepted by the via of http and tcp conditions
+var Vias = []string{"pod", "host"}
+
+// checkVia will fill in the default via (pod) and make sure any other via is one of Vias
+func checkVia(via string) (string, error) {
+	if via == "" {
+		return "pod", nil
+	}
+	for _, item := range Vias {
+		if via == item {
+			return via, nil
+		}
+	}
+	return via, errors.New(fmt.Sprintf("Invalid via %s.  Must be one of %v", via, Vias))
+}
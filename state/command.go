@@ -0,0 +1,100 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dansteen/constellation/util"
+)
+
+// CommandRunner runs a command inside a container and returns its exit code.  An error is returned if the command could
+// not be run at all (e.g. the pod is not up yet).
+type CommandRunner func(command []string, timeout time.Duration) (int, error)
+
+type CommandCondition struct {
+	Command  []string `json:"command"`
+	Codes    []int    `json:"codes"`
+	Interval int64    `json:"interval"`
+	Timeout  int64    `json:"timeout"`
+	Status   string   `json:"status"`
+}
+
+func (cond *CommandCondition) UnmarshalJSON(b []byte) error {
+	// create a string version of our condition
+	type StringCommandCondition struct {
+		Command  string `json:"command"`
+		Codes    []int  `json:"codes"`
+		Interval int64  `json:"interval"`
+		Timeout  int64  `json:"timeout"`
+		Status   string `json:"status"`
+	}
+	var stringCond StringCommandCondition
+	// unmarshal our items into it
+	err := json.Unmarshal(b, &stringCond)
+	if err != nil {
+		return err
+	}
+
+	// split our command the same way exec is split
+	cond.Command = util.ShellSplit(stringCond.Command)
+	if len(cond.Command) == 0 || cond.Command[0] == "" {
+		return errors.New("Command state conditions require a command")
+	}
+	// and fill in our defaults
+	if len(stringCond.Codes) == 0 {
+		stringCond.Codes = []int{0}
+	}
+	if stringCond.Interval <= 0 {
+		stringCond.Interval = 1
+	}
+	if stringCond.Timeout <= 0 {
+		stringCond.Timeout = 10
+	}
+	cond.Codes = stringCond.Codes
+	cond.Interval = stringCond.Interval
+	cond.Timeout = stringCond.Timeout
+	cond.Status = stringCond.Status
+	return nil
+}
+
+// Handle will periodically run our command inside the container until it exits with one of our codes
+func (cond *CommandCondition) Handle(run CommandRunner, results chan<- error, stop <-chan bool, logger *log.Logger) {
+	command := strings.Join(cond.Command, " ")
+	logger.Printf("Running '%s' until it exits with %+v\n", command, cond.Codes)
+	ticker := time.NewTicker(time.Second * time.Duration(cond.Interval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			exitCode, err := run(cond.Command, time.Second*time.Duration(cond.Timeout))
+			// our pod may not be up yet
+			if err != nil {
+				continue
+			}
+			found := false
+			for _, code := range cond.Codes {
+				if code == exitCode {
+					found = true
+				}
+			}
+			if !found {
+				continue
+			}
+			logger.Printf("'%s' exited with %d.\n", command, exitCode)
+			switch cond.Status {
+			case "success":
+				results <- nil
+			case "failure":
+				results <- errors.New(fmt.Sprintf("'%s' exited with %d. Specified as failure\n", command, exitCode))
+			}
+			return
+		}
+	}
+}
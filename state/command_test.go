@@ -0,0 +1,121 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandConditionUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected CommandCondition
+		err      string
+	}{
+		{
+			name:     "defaults",
+			json:     `{"command": "pg_isready", "status": "success"}`,
+			expected: CommandCondition{Command: []string{"pg_isready"}, Codes: []int{0}, Interval: 1, Timeout: 10, Status: "success"},
+		},
+		{
+			name: "split like exec",
+			json: `{"command": "sh -c 'test -f /ready'", "codes": [0, 3], "interval": 2, "timeout": 1, "status": "failure"}`,
+			expected: CommandCondition{Command: []string{"sh", "-c", "test -f /ready"}, Codes: []int{0, 3}, Interval: 2, Timeout: 1,
+				Status: "failure"},
+		},
+		{
+			name: "no command",
+			json: `{"status": "success"}`,
+			err:  "Command state conditions require a command",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cond CommandCondition
+			err := json.Unmarshal([]byte(test.json), &cond)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cond, test.expected) {
+				t.Errorf("unmarshalled %+v, expected %+v", cond, test.expected)
+			}
+		})
+	}
+}
+
+func TestCommandConditionHandle(t *testing.T) {
+	// each run of the command exits with the next of exits.  -1 means the command could not be run
+	tests := []struct {
+		name   string
+		codes  []int
+		status string
+		exits  []int
+		err    string
+	}{
+		{
+			name:   "success once the pod is up",
+			codes:  []int{0},
+			status: "success",
+			exits:  []int{-1, 0},
+		},
+		{
+			name:   "success once a code matches",
+			codes:  []int{0, 2},
+			status: "success",
+			exits:  []int{1, 2},
+		},
+		{
+			name:   "failure",
+			codes:  []int{5},
+			status: "failure",
+			exits:  []int{5},
+			err:    "'check --ready' exited with 5. Specified as failure",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runs := 0
+			run := func(command []string, timeout time.Duration) (int, error) {
+				if !reflect.DeepEqual(command, []string{"check", "--ready"}) || timeout != time.Second {
+					t.Errorf("ran %v with a timeout of %s", command, timeout)
+				}
+				exit := test.exits[runs]
+				runs++
+				if exit < 0 {
+					return 0, errors.New("no pod")
+				}
+				return exit, nil
+			}
+			cond := CommandCondition{Command: []string{"check", "--ready"}, Codes: test.codes, Interval: 1, Timeout: 1, Status: test.status}
+			results := make(chan error, 1)
+			go cond.Handle(run, results, make(chan bool), log.New(ioutil.Discard, "", 0))
+			select {
+			case err := <-results:
+				if test.err == "" && err != nil {
+					t.Errorf("expected success, got %v", err)
+				} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+					t.Errorf("expected an error containing %q, got %v", test.err, err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no result was reported")
+			}
+			if runs != len(test.exits) {
+				t.Errorf("the command was run %d times, expected %d", runs, len(test.exits))
+			}
+		})
+	}
+}
@@ -9,37 +9,56 @@ import (
 )
 
 type ExitCondition struct {
-	Codes  []int  `json:codes`
-	Status string `json:status`
+	Codes  []int  `json:"codes"`
+	Status string `json:"status"`
 }
 
-func (cond *ExitCondition) Handle(command *exec.Cmd, results chan<- error, stop <-chan bool, logger *log.Logger) {
-	logger.Printf("Waiting for Exit %+v\n", cond.Codes)
-	// report the results of our wait
-	waitResult := make(chan error)
-	// start a command wait
-	go func(command *exec.Cmd, result chan<- error) {
-		select {
-		case result <- command.Wait():
-		case <-stop:
-			return
+// ExitWaiter waits for a command to exit and makes its exit code available to any number of exit conditions, since a
+// command can only be waited on once.
+type ExitWaiter struct {
+	done     chan bool
+	exitCode int
+}
+
+// NewExitWaiter will create a new ExitWaiter
+func NewExitWaiter() *ExitWaiter {
+	return &ExitWaiter{done: make(chan bool)}
+}
+
+// Wait will wait for command to exit and record its exit code.  It must be called once the command has been started.
+func (waiter *ExitWaiter) Wait(command *exec.Cmd) {
+	err := command.Wait()
+	if err != nil {
+		if exiterr, ok := err.(*exec.ExitError); ok {
+			// The program has exited with an exit code != 0
+			if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
+				waiter.exitCode = status.ExitStatus()
+			}
 		}
-	}(command, waitResult)
+	} else {
+		waiter.exitCode = 0
+	}
+	close(waiter.done)
+}
+
+// Done is closed once the command has exited
+func (waiter *ExitWaiter) Done() <-chan bool {
+	return waiter.done
+}
+
+// ExitCode returns the exit code of the command.  It is only valid once Done is closed
+func (waiter *ExitWaiter) ExitCode() int {
+	return waiter.exitCode
+}
+
+func (cond *ExitCondition) Handle(waiter *ExitWaiter, results chan<- error, stop <-chan bool, logger *log.Logger) {
+	logger.Printf("Waiting for Exit %+v\n", cond.Codes)
 
 	// wait for the command to exit and grab the exit code or listen for a stop command
 	var exitCode int
 	select {
-	case err := <-waitResult:
-		if err != nil {
-			if exiterr, ok := err.(*exec.ExitError); ok {
-				// The program has exited with an exit code != 0
-				if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-					exitCode = status.ExitStatus()
-				}
-			}
-		} else {
-			exitCode = 0
-		}
+	case <-waiter.Done():
+		exitCode = waiter.ExitCode()
 		logger.Printf("Received Exit Code: %d\n", exitCode)
 	case <-stop:
 		return
@@ -5,22 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"regexp"
+	"os"
 
 	"github.com/hpcloud/tail"
 )
 
 type FileMonitorCondition struct {
-	File   string         `json:"file"`
-	Regex  *regexp.Regexp `json:"regex"`
-	Status string         `json:"status"`
+	File string `json:"file"`
+	Matcher
+	Status string `json:"status"`
 }
 
 func (monitor *FileMonitorCondition) UnmarshalJSON(b []byte) error {
 	// create a string version of our monitor
 	type StringMonitor struct {
-		File   string `json:"file"`
-		String string `json:"regex"`
+		File string `json:"file"`
+		stringMatcher
 		Status string `json:"status"`
 	}
 	var stringMonitor StringMonitor
@@ -30,31 +30,36 @@ func (monitor *FileMonitorCondition) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	// then convert ouf String to a regex
-	regex, err := regexp.Compile(stringMonitor.String)
+	// then convert our String (or Sequence) to a matcher
+	matcher, err := stringMonitor.matcher()
 	if err != nil {
-		return err
+		return errors.New(fmt.Sprintf("Invalid filemonitor condition for %s: %s", stringMonitor.File, err))
 	}
 	// then create a new FileMonitorCondition with our new values
 	monitor.File = stringMonitor.File
 	monitor.Status = stringMonitor.Status
-	monitor.Regex = regex
+	monitor.Matcher = matcher
 	return nil
 }
 
 // handleFile handler for actual files
 func (monitor *FileMonitorCondition) Handle(results chan<- error, stop <-chan bool, logger *log.Logger) {
-	logger.Printf("Monitoring %s for %s\n", monitor.File, monitor.Regex.String())
+	logger.Printf("Monitoring %s for %s\n", monitor.File, monitor.Matcher)
 
-	// tail our file. We seek to the end first
+	// tail our file. We seek to the end first so that old lines don't match.  If the file doesn't exist yet, everything
+	// written to it is new, so we read it from the start once it shows up.
+	location := &tail.SeekInfo{
+		Offset: 0,
+		Whence: 2,
+	}
+	if _, err := os.Stat(monitor.File); os.IsNotExist(err) {
+		location = nil
+	}
 	tail, err := tail.TailFile(monitor.File, tail.Config{
 		Follow:    true,
 		ReOpen:    true,
 		MustExist: false,
-		Location: &tail.SeekInfo{
-			Offset: 0,
-			Whence: 2,
-		},
+		Location:  location,
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -66,13 +71,13 @@ func (monitor *FileMonitorCondition) Handle(results chan<- error, stop <-chan bo
 			tail.Stop()
 			return
 		case line := <-tail.Lines:
-			if monitor.Regex.Match([]byte(line.Text)) == true {
+			if monitor.Match(line.Text) == true {
 				if monitor.Status == "success" {
-					logger.Printf("Matched %s to %s. Success.\n", monitor.File, monitor.Regex.String())
+					logger.Printf("Matched %s to %s. Success.\n", monitor.File, monitor.Matcher)
 					results <- nil
 				}
 				if monitor.Status == "failure" {
-					results <- errors.New(fmt.Sprintf("Matched %s to %s. Specified as failure\n", monitor.File, monitor.Regex.String()))
+					results <- errors.New(fmt.Sprintf("Matched %s to %s. Specified as failure\n", monitor.File, monitor.Matcher))
 				}
 				// stop tailing
 				tail.Stop()
@@ -0,0 +1,139 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+type HTTPCondition struct {
+	Path     string         `json:"path"`
+	Port     string         `json:"port"`
+	Via      string         `json:"via"`
+	Codes    []int          `json:"codes"`
+	Body     *regexp.Regexp `json:"body"`
+	Interval int64          `json:"interval"`
+	Timeout  int64          `json:"timeout"`
+	Status   string         `json:"status"`
+}
+
+func (cond *HTTPCondition) UnmarshalJSON(b []byte) error {
+	// create a string version of our condition
+	type StringHTTPCondition struct {
+		Path     string `json:"path"`
+		Port     string `json:"port"`
+		Via      string `json:"via"`
+		Codes    []int  `json:"codes"`
+		Body     string `json:"body"`
+		Interval int64  `json:"interval"`
+		Timeout  int64  `json:"timeout"`
+		Status   string `json:"status"`
+	}
+	var stringCond StringHTTPCondition
+	// unmarshal our items into it
+	err := json.Unmarshal(b, &stringCond)
+	if err != nil {
+		return err
+	}
+
+	// then convert our body to a regex if we have one
+	if stringCond.Body != "" {
+		cond.Body, err = regexp.Compile(stringCond.Body)
+		if err != nil {
+			return err
+		}
+	}
+	// and fill in our defaults
+	if stringCond.Path == "" {
+		stringCond.Path = "/"
+	}
+	stringCond.Via, err = checkVia(stringCond.Via)
+	if err != nil {
+		return err
+	}
+	if len(stringCond.Codes) == 0 {
+		stringCond.Codes = []int{200}
+	}
+	if stringCond.Interval <= 0 {
+		stringCond.Interval = 1
+	}
+	if stringCond.Timeout <= 0 {
+		stringCond.Timeout = 5
+	}
+	cond.Path = stringCond.Path
+	cond.Port = stringCond.Port
+	cond.Via = stringCond.Via
+	cond.Codes = stringCond.Codes
+	cond.Interval = stringCond.Interval
+	cond.Timeout = stringCond.Timeout
+	cond.Status = stringCond.Status
+	return nil
+}
+
+// Handle will poll our url until it returns one of our codes (and matches our body regex if we have one)
+func (cond *HTTPCondition) Handle(resolve AddressResolver, results chan<- error, stop <-chan bool, logger *log.Logger) {
+	logger.Printf("Polling %s on port %s for %+v\n", cond.Path, cond.Port, cond.Codes)
+	client := &http.Client{Timeout: time.Second * time.Duration(cond.Timeout)}
+	ticker := time.NewTicker(time.Second * time.Duration(cond.Interval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			url, matched := cond.poll(client, resolve)
+			if !matched {
+				continue
+			}
+			logger.Printf("%s matched.\n", url)
+			switch cond.Status {
+			case "success":
+				results <- nil
+			case "failure":
+				results <- errors.New(fmt.Sprintf("%s matched. Specified as failure\n", url))
+			}
+			return
+		}
+	}
+}
+
+// poll will make a single request and check if it matches
+func (cond *HTTPCondition) poll(client *http.Client, resolve AddressResolver) (string, bool) {
+	address, err := resolve(cond.Port, cond.Via)
+	// our pod may not be up yet
+	if err != nil {
+		return "", false
+	}
+	url := fmt.Sprintf("http://%s%s", address, cond.Path)
+	response, err := client.Get(url)
+	if err != nil {
+		return url, false
+	}
+	defer response.Body.Close()
+
+	// check our code
+	found := false
+	for _, code := range cond.Codes {
+		if code == response.StatusCode {
+			found = true
+		}
+	}
+	if !found {
+		return url, false
+	}
+
+	// and our body
+	if cond.Body != nil {
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil || !cond.Body.Match(body) {
+			return url, false
+		}
+	}
+	return url, true
+}
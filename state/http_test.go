@@ -0,0 +1,164 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPConditionUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected HTTPCondition
+		err      string
+	}{
+		{
+			name:     "defaults",
+			json:     `{"port": "http", "status": "success"}`,
+			expected: HTTPCondition{Path: "/", Port: "http", Via: "pod", Codes: []int{200}, Interval: 1, Timeout: 5, Status: "success"},
+		},
+		{
+			name: "everything set",
+			json: `{"path": "/health", "port": "admin", "via": "host", "codes": [200, 204], "interval": 2, "timeout": 10, "status": "failure"}`,
+			expected: HTTPCondition{Path: "/health", Port: "admin", Via: "host", Codes: []int{200, 204}, Interval: 2, Timeout: 10,
+				Status: "failure"},
+		},
+		{
+			name: "unknown via",
+			json: `{"port": "http", "via": "hots", "status": "success"}`,
+			err:  "Invalid via hots.  Must be one of [pod host]",
+		},
+		{
+			name: "bad body regex",
+			json: `{"port": "http", "body": "(", "status": "success"}`,
+			err:  "missing closing )",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cond HTTPCondition
+			err := json.Unmarshal([]byte(test.json), &cond)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cond, test.expected) {
+				t.Errorf("unmarshalled %+v, expected %+v", cond, test.expected)
+			}
+		})
+	}
+}
+
+func TestHTTPConditionPoll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/ready":
+			fmt.Fprint(writer, `{"status": "ready"}`)
+		case "/starting":
+			fmt.Fprint(writer, `{"status": "starting"}`)
+		default:
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name    string
+		json    string
+		resolve AddressResolver
+		matched bool
+	}{
+		{
+			name:    "default code",
+			json:    `{"path": "/ready", "port": "http"}`,
+			matched: true,
+		},
+		{
+			name: "other code",
+			json: `{"path": "/down", "port": "http"}`,
+		},
+		{
+			name:    "listed code",
+			json:    `{"path": "/down", "port": "http", "codes": [200, 503]}`,
+			matched: true,
+		},
+		{
+			name:    "body matches",
+			json:    `{"path": "/ready", "port": "http", "body": "\"ready\""}`,
+			matched: true,
+		},
+		{
+			name: "body does not match",
+			json: `{"path": "/starting", "port": "http", "body": "\"ready\""}`,
+		},
+		{
+			name:    "pod not up yet",
+			json:    `{"path": "/ready", "port": "http"}`,
+			resolve: func(port string, via string) (string, error) { return "", errors.New("no pod") },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cond HTTPCondition
+			err := json.Unmarshal([]byte(test.json), &cond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resolve := test.resolve
+			if resolve == nil {
+				resolve = func(port string, via string) (string, error) {
+					if port != "http" || via != "pod" {
+						t.Errorf("resolved port %s via %s", port, via)
+					}
+					return address, nil
+				}
+			}
+			_, matched := cond.poll(&http.Client{Timeout: time.Second}, resolve)
+			if matched != test.matched {
+				t.Errorf("poll matched %t, expected %t", matched, test.matched)
+			}
+		})
+	}
+}
+
+func TestHTTPConditionHandle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer server.Close()
+	resolve := func(port string, via string) (string, error) {
+		return strings.TrimPrefix(server.URL, "http://"), nil
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+
+	for _, status := range []string{"success", "failure"} {
+		t.Run(status, func(t *testing.T) {
+			cond := HTTPCondition{Path: "/", Port: "http", Via: "pod", Codes: []int{200}, Interval: 1, Timeout: 1, Status: status}
+			results := make(chan error, 1)
+			go cond.Handle(resolve, results, make(chan bool), logger)
+			select {
+			case err := <-results:
+				if (err == nil) != (status == "success") {
+					t.Errorf("a %s condition reported %v", status, err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no result was reported")
+			}
+		})
+	}
+}
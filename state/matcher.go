@@ -0,0 +1,126 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matcher checks lines of output for a regex, or for an ordered sequence of regexes, and only triggers once that has
+// been seen Count times.  Each Matcher keeps track of what it has seen so far, so every condition needs its own copy
+type Matcher struct {
+	Regex    *regexp.Regexp   `json:"regex"`
+	Sequence []*regexp.Regexp `json:"sequence"`
+	Count    int              `json:"count"`
+
+	// position is the index of the next regex we are looking for in our sequence
+	position int
+	// matches is the number of times we have seen our full sequence
+	matches int
+	// onCapture is called with the named capture groups of each line that matches one of our regexes
+	onCapture func(map[string]string)
+}
+
+// stringMatcher is a Matcher the way it is written in a config file.  It is embedded in the string versions of the
+// conditions that use a Matcher, which they are unmarshalled into before being converted.
+type stringMatcher struct {
+	String   string   `json:"regex"`
+	Sequence []string `json:"sequence"`
+	Count    int      `json:"count"`
+}
+
+// matcher will compile our String (or Sequence) into a Matcher.  Exactly one of them must be provided.  A count of 0 is
+// the same as a count of 1
+func (stringMatcher stringMatcher) matcher() (Matcher, error) {
+	regex, sequence, count := stringMatcher.String, stringMatcher.Sequence, stringMatcher.Count
+	matcher := Matcher{Count: count}
+	if regex != "" && len(sequence) > 0 {
+		return matcher, errors.New("Only one of regex and sequence can be set")
+	}
+	if regex == "" && len(sequence) == 0 {
+		return matcher, errors.New("One of regex or sequence must be set")
+	}
+	if count < 0 {
+		return matcher, errors.New(fmt.Sprintf("Count must be a positive number, not %d", count))
+	}
+
+	if regex != "" {
+		compiled, err := regexp.Compile(regex)
+		if err != nil {
+			return matcher, err
+		}
+		matcher.Regex = compiled
+	}
+	for _, item := range sequence {
+		compiled, err := regexp.Compile(item)
+		if err != nil {
+			return matcher, err
+		}
+		matcher.Sequence = append(matcher.Sequence, compiled)
+	}
+	return matcher, nil
+}
+
+// Match will check line against the regex we are waiting on, and return true once we have seen everything we need to
+func (matcher *Matcher) Match(line string) bool {
+	pattern := matcher.Sequence
+	if len(pattern) == 0 {
+		pattern = []*regexp.Regexp{matcher.Regex}
+	}
+
+	regex := pattern[matcher.position]
+	match := regex.FindStringSubmatch(line)
+	if match == nil {
+		return false
+	}
+	matcher.capture(regex, match)
+	matcher.position++
+	// we have not finished our sequence yet
+	if matcher.position < len(pattern) {
+		return false
+	}
+	// we have, so count it and start looking for the next one
+	matcher.position = 0
+	matcher.matches++
+	return matcher.matches >= matcher.Count
+}
+
+// OnCapture sets a function to call with the named capture groups of every line that matches one of our regexes
+func (matcher *Matcher) OnCapture(onCapture func(map[string]string)) {
+	matcher.onCapture = onCapture
+}
+
+// capture will pass the named capture groups of match on to onCapture
+func (matcher *Matcher) capture(regex *regexp.Regexp, match []string) {
+	if matcher.onCapture == nil {
+		return
+	}
+	captures := make(map[string]string)
+	for index, name := range regex.SubexpNames() {
+		if index > 0 && name != "" {
+			captures[name] = match[index]
+		}
+	}
+	if len(captures) > 0 {
+		matcher.onCapture(captures)
+	}
+}
+
+// String will describe what we are looking for
+func (matcher Matcher) String() string {
+	description := ""
+	if len(matcher.Sequence) > 0 {
+		regexes := make([]string, len(matcher.Sequence))
+		for index, regex := range matcher.Sequence {
+			regexes[index] = regex.String()
+		}
+		description = fmt.Sprintf("sequence [%s]", strings.Join(regexes, ", "))
+	} else if matcher.Regex != nil {
+		description = matcher.Regex.String()
+	}
+	if matcher.Count > 1 {
+		description = fmt.Sprintf("%s (%d times)", description, matcher.Count)
+	}
+	return description
+}
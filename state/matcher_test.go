@@ -0,0 +1,183 @@
+package state
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatcherUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected string
+		err      string
+	}{
+		{
+			name:     "regex",
+			json:     `{"source": "STDOUT", "regex": "ready", "status": "success"}`,
+			expected: "ready",
+		},
+		{
+			name:     "count",
+			json:     `{"source": "STDOUT", "regex": "ready", "count": 3, "status": "success"}`,
+			expected: "ready (3 times)",
+		},
+		{
+			name:     "sequence",
+			json:     `{"source": "STDOUT", "sequence": ["starting", "ready"], "status": "success"}`,
+			expected: "sequence [starting, ready]",
+		},
+		{
+			name: "neither",
+			json: `{"source": "STDOUT", "status": "success"}`,
+			err:  "Invalid output condition for STDOUT: One of regex or sequence must be set",
+		},
+		{
+			name: "both",
+			json: `{"source": "STDOUT", "regex": "ready", "sequence": ["ready"], "status": "success"}`,
+			err:  "Only one of regex and sequence can be set",
+		},
+		{
+			name: "negative count",
+			json: `{"source": "STDOUT", "regex": "ready", "count": -1, "status": "success"}`,
+			err:  "Count must be a positive number, not -1",
+		},
+		{
+			name: "bad regex",
+			json: `{"source": "STDOUT", "sequence": ["ready", "["], "status": "success"}`,
+			err:  "missing closing ]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var condition OutputCondition
+			err := json.Unmarshal([]byte(test.json), &condition)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if condition.Matcher.String() != test.expected {
+				t.Errorf("matcher is %q, expected %q", condition.Matcher.String(), test.expected)
+			}
+		})
+	}
+}
+
+func TestMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		matcher  stringMatcher
+		lines    []string
+		expected []bool
+	}{
+		{
+			name:     "regex",
+			matcher:  stringMatcher{String: "ready"},
+			lines:    []string{"starting", "ready", "ready"},
+			expected: []bool{false, true, true},
+		},
+		{
+			name:     "count",
+			matcher:  stringMatcher{String: "connected", Count: 2},
+			lines:    []string{"connected", "starting", "connected", "connected"},
+			expected: []bool{false, false, true, true},
+		},
+		{
+			name:     "sequence",
+			matcher:  stringMatcher{Sequence: []string{"migrating", "migrated"}},
+			lines:    []string{"migrated", "migrating", "working", "migrated"},
+			expected: []bool{false, false, false, true},
+		},
+		{
+			name:     "sequence with a count",
+			matcher:  stringMatcher{Sequence: []string{"begin", "end"}, Count: 2},
+			lines:    []string{"begin", "end", "end", "begin", "end"},
+			expected: []bool{false, false, false, false, true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matcher, err := test.matcher.matcher()
+			if err != nil {
+				t.Fatal(err)
+			}
+			matched := make([]bool, len(test.lines))
+			for index, line := range test.lines {
+				matched[index] = matcher.Match(line)
+			}
+			if !reflect.DeepEqual(matched, test.expected) {
+				t.Errorf("matched %v, expected %v", matched, test.expected)
+			}
+		})
+	}
+}
+
+func TestMatcherCaptures(t *testing.T) {
+	tests := []struct {
+		name     string
+		matcher  stringMatcher
+		lines    []string
+		expected []map[string]string
+	}{
+		{
+			name:     "named groups",
+			matcher:  stringMatcher{String: `token: (?P<token>\w+) for (?P<user>\w+)`},
+			lines:    []string{"starting", "token: abc123 for admin"},
+			expected: []map[string]string{{"token": "abc123", "user": "admin"}},
+		},
+		{
+			name:     "unnamed groups are left out",
+			matcher:  stringMatcher{String: `(ready) on (?P<port>\d+)`},
+			lines:    []string{"ready on 8080"},
+			expected: []map[string]string{{"port": "8080"}},
+		},
+		{
+			name:    "no named groups",
+			matcher: stringMatcher{String: `(ready)`},
+			lines:   []string{"ready"},
+		},
+		{
+			name:     "each line of a sequence",
+			matcher:  stringMatcher{Sequence: []string{`port (?P<port>\d+)`, `version (?P<version>\S+)`}},
+			lines:    []string{"version 0.9", "port 5432", "version 1.2"},
+			expected: []map[string]string{{"port": "5432"}, {"version": "1.2"}},
+		},
+		{
+			name:     "every match of a count",
+			matcher:  stringMatcher{String: `worker (?P<worker>\d+) up`, Count: 2},
+			lines:    []string{"worker 1 up", "worker 2 up"},
+			expected: []map[string]string{{"worker": "1"}, {"worker": "2"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matcher, err := test.matcher.matcher()
+			if err != nil {
+				t.Fatal(err)
+			}
+			captured := make([]map[string]string, 0)
+			matcher.OnCapture(func(captures map[string]string) {
+				captured = append(captured, captures)
+			})
+			for _, line := range test.lines {
+				matcher.Match(line)
+			}
+			if test.expected == nil {
+				test.expected = make([]map[string]string, 0)
+			}
+			if !reflect.DeepEqual(captured, test.expected) {
+				t.Errorf("captured %v, expected %v", captured, test.expected)
+			}
+		})
+	}
+}
@@ -5,20 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"regexp"
 )
 
 type OutputCondition struct {
-	Source string         `json:"source"`
-	Regex  *regexp.Regexp `json:"regex"`
-	Status string         `json:"status"`
+	Source string `json:"source"`
+	Matcher
+	Status string `json:"status"`
 }
 
 func (monitor *OutputCondition) UnmarshalJSON(b []byte) error {
 	// create a string version of our monitor
 	type StringMonitor struct {
 		Source string `json:"source"`
-		String string `json:"regex"`
+		stringMatcher
 		Status string `json:"status"`
 	}
 	var stringMonitor StringMonitor
@@ -28,31 +27,32 @@ func (monitor *OutputCondition) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	// then convert ouf String to a regex
-	regex, err := regexp.Compile(stringMonitor.String)
+	// then convert our String (or Sequence) to a matcher
+	matcher, err := stringMonitor.matcher()
 	if err != nil {
-		return err
+		return errors.New(fmt.Sprintf("Invalid output condition for %s: %s", stringMonitor.Source, err))
 	}
 	// then create a new OutputCondition with our new values
 	monitor.Source = stringMonitor.Source
 	monitor.Status = stringMonitor.Status
-	monitor.Regex = regex
+	monitor.Matcher = matcher
 	return nil
 }
 
 // Handle will handle the output condition.  This handler is different than the ones for the other conditions in/
-// that it expects a different process to manage the streams.
-func (monitor *OutputCondition) Handle(logLine string, results chan<- error, stop <-chan bool, logger *log.Logger) {
+// that it expects a different process to manage the streams.  It returns true once the condition has reported a result
+func (monitor *OutputCondition) Handle(logLine string, results chan<- error, stop <-chan bool, logger *log.Logger) bool {
 	// then check for our regexs
 	// once we find a match we are done
-	if monitor.Regex.Match([]byte(logLine)) == true {
-		logger.Printf("%s matched %s.\n", monitor.Source, monitor.Regex.String())
+	if monitor.Match(logLine) == true {
+		logger.Printf("%s matched %s.\n", monitor.Source, monitor.Matcher)
 		if monitor.Status == "success" {
 			results <- nil
 		}
 		if monitor.Status == "failure" {
-			results <- errors.New(fmt.Sprintf("%s matched %s. Specified as failure\n", monitor.Source, monitor.Regex.String()))
+			results <- errors.New(fmt.Sprintf("%s matched %s. Specified as failure\n", monitor.Source, monitor.Matcher))
 		}
-		return
+		return true
 	}
+	return false
 }
@@ -1,14 +1,28 @@
 package state
 
+import (
+	"fmt"
+	"strings"
+)
+
 // StateCondition holds information about a specific state condition, and also functions to use that state condition
 type StateConditions struct {
 	Exit         *ExitCondition         `json:"exit"`
 	Timeout      *TimeoutCondition      `json:"timeout"`
 	FileMonitors []FileMonitorCondition `json:"filemonitor"`
 	Outputs      []OutputCondition      `json:"output"`
+	HTTP         []HTTPCondition        `json:"http"`
+	TCP          []TCPCondition         `json:"tcp"`
+	Commands     []CommandCondition     `json:"command"`
+	// All succeeds once every block in it succeeds, and fails as soon as one fails
+	All []StateConditions `json:"all"`
+	// Any succeeds as soon as one block in it succeeds, and fails once every block fails
+	Any []StateConditions `json:"any"`
+	// Not fails if its block succeeds, and succeeds if its block fails
+	Not *StateConditions `json:"not"`
 }
 
-// Count returns the total number of state conditions we have
+// Count returns the total number of state conditions we have.  all, any and not blocks count as a single condition each
 func (state *StateConditions) Count() int {
 	count := 0
 	if state.Exit != nil {
@@ -19,5 +33,99 @@ func (state *StateConditions) Count() int {
 	}
 	count += len(state.FileMonitors)
 	count += len(state.Outputs)
+	count += len(state.HTTP)
+	count += len(state.TCP)
+	count += len(state.Commands)
+	if len(state.All) > 0 {
+		count++
+	}
+	if len(state.Any) > 0 {
+		count++
+	}
+	if state.Not != nil {
+		count++
+	}
 	return count
 }
+
+// Walk will call visit on this block of state conditions and on every block nested in it
+func (state *StateConditions) Walk(visit func(*StateConditions) error) error {
+	err := visit(state)
+	if err != nil {
+		return err
+	}
+	for index := range state.All {
+		err = state.All[index].Walk(visit)
+		if err != nil {
+			return err
+		}
+	}
+	for index := range state.Any {
+		err = state.Any[index].Walk(visit)
+		if err != nil {
+			return err
+		}
+	}
+	if state.Not != nil {
+		return state.Not.Walk(visit)
+	}
+	return nil
+}
+
+// HasExit checks if there is an exit condition anywhere in this block of state conditions
+func (state *StateConditions) HasExit() bool {
+	found := false
+	state.Walk(func(block *StateConditions) error {
+		if block.Exit != nil {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+// Types lists the types of the conditions in this block, in the order they are listed in StateConditions.  Groups are
+// listed with the types in each of their blocks, e.g. any(http; tcp, timeout)
+func (state *StateConditions) Types() []string {
+	types := make([]string, 0)
+	if state.Exit != nil {
+		types = append(types, "exit")
+	}
+	if state.Timeout != nil {
+		types = append(types, "timeout")
+	}
+	if len(state.FileMonitors) > 0 {
+		types = append(types, "filemonitor")
+	}
+	if len(state.Outputs) > 0 {
+		types = append(types, "output")
+	}
+	if len(state.HTTP) > 0 {
+		types = append(types, "http")
+	}
+	if len(state.TCP) > 0 {
+		types = append(types, "tcp")
+	}
+	if len(state.Commands) > 0 {
+		types = append(types, "command")
+	}
+	if len(state.All) > 0 {
+		types = append(types, fmt.Sprintf("all(%s)", groupTypes(state.All)))
+	}
+	if len(state.Any) > 0 {
+		types = append(types, fmt.Sprintf("any(%s)", groupTypes(state.Any)))
+	}
+	if state.Not != nil {
+		types = append(types, fmt.Sprintf("not(%s)", strings.Join(state.Not.Types(), ", ")))
+	}
+	return types
+}
+
+// groupTypes lists the types in each block of an all or any group
+func groupTypes(blocks []StateConditions) string {
+	types := make([]string, len(blocks))
+	for index := range blocks {
+		types[index] = strings.Join(blocks[index].Types(), ", ")
+	}
+	return strings.Join(types, "; ")
+}
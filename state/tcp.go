@@ -0,0 +1,75 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+type TCPCondition struct {
+	Port     string `json:"port"`
+	Via      string `json:"via"`
+	Interval int64  `json:"interval"`
+	Timeout  int64  `json:"timeout"`
+	Status   string `json:"status"`
+}
+
+func (cond *TCPCondition) UnmarshalJSON(b []byte) error {
+	// create a plain version of our condition so we don't recurse
+	type PlainTCPCondition TCPCondition
+	var plainCond PlainTCPCondition
+	err := json.Unmarshal(b, &plainCond)
+	if err != nil {
+		return err
+	}
+
+	// fill in our defaults
+	plainCond.Via, err = checkVia(plainCond.Via)
+	if err != nil {
+		return err
+	}
+	if plainCond.Interval <= 0 {
+		plainCond.Interval = 1
+	}
+	if plainCond.Timeout <= 0 {
+		plainCond.Timeout = 5
+	}
+	*cond = TCPCondition(plainCond)
+	return nil
+}
+
+// Handle will repeatedly try to connect to our port until a connection succeeds
+func (cond *TCPCondition) Handle(resolve AddressResolver, results chan<- error, stop <-chan bool, logger *log.Logger) {
+	logger.Printf("Waiting for port %s to accept connections\n", cond.Port)
+	ticker := time.NewTicker(time.Second * time.Duration(cond.Interval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// our pod may not be up yet
+			address, err := resolve(cond.Port, cond.Via)
+			if err != nil {
+				continue
+			}
+			conn, err := net.DialTimeout("tcp", address, time.Second*time.Duration(cond.Timeout))
+			if err != nil {
+				continue
+			}
+			conn.Close()
+			logger.Printf("Connected to %s (%s).\n", cond.Port, address)
+			switch cond.Status {
+			case "success":
+				results <- nil
+			case "failure":
+				results <- errors.New(fmt.Sprintf("Connected to %s (%s). Specified as failure\n", cond.Port, address))
+			}
+			return
+		}
+	}
+}
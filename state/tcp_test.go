@@ -0,0 +1,125 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTCPConditionUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected TCPCondition
+		err      string
+	}{
+		{
+			name:     "defaults",
+			json:     `{"port": "db", "status": "success"}`,
+			expected: TCPCondition{Port: "db", Via: "pod", Interval: 1, Timeout: 5, Status: "success"},
+		},
+		{
+			name:     "everything set",
+			json:     `{"port": "db", "via": "host", "interval": 3, "timeout": 1, "status": "failure"}`,
+			expected: TCPCondition{Port: "db", Via: "host", Interval: 3, Timeout: 1, Status: "failure"},
+		},
+		{
+			name: "unknown via",
+			json: `{"port": "db", "via": "node", "status": "success"}`,
+			err:  "Invalid via node.  Must be one of [pod host]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cond TCPCondition
+			err := json.Unmarshal([]byte(test.json), &cond)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cond, test.expected) {
+				t.Errorf("unmarshalled %+v, expected %+v", cond, test.expected)
+			}
+		})
+	}
+}
+
+func TestTCPConditionHandle(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	// the first attempt is made before our pod is up
+	attempts := 0
+	resolve := func(port string, via string) (string, error) {
+		attempts++
+		if attempts == 1 {
+			return "", errors.New("no pod")
+		}
+		return listener.Addr().String(), nil
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+
+	for _, status := range []string{"success", "failure"} {
+		t.Run(status, func(t *testing.T) {
+			attempts = 0
+			cond := TCPCondition{Port: "db", Via: "pod", Interval: 1, Timeout: 1, Status: status}
+			results := make(chan error, 1)
+			go cond.Handle(resolve, results, make(chan bool), logger)
+			select {
+			case err := <-results:
+				if (err == nil) != (status == "success") {
+					t.Errorf("a %s condition reported %v", status, err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no result was reported")
+			}
+			if attempts != 2 {
+				t.Errorf("expected the port to be resolved twice, not %d times", attempts)
+			}
+		})
+	}
+}
+
+func TestTCPConditionStop(t *testing.T) {
+	cond := TCPCondition{Port: "db", Via: "pod", Interval: 1, Timeout: 1, Status: "success"}
+	resolve := func(port string, via string) (string, error) { return "", errors.New("no pod") }
+	results := make(chan error, 1)
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		cond.Handle(resolve, results, stop, log.New(ioutil.Discard, "", 0))
+		close(done)
+	}()
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the condition did not stop")
+	}
+	if len(results) != 0 {
+		t.Errorf("a stopped condition reported %v", <-results)
+	}
+}
@@ -8,8 +8,8 @@ import (
 )
 
 type TimeoutCondition struct {
-	Duration int64  `json:duration`
-	Status   string `json:status`
+	Duration int64  `json:"duration"`
+	Status   string `json:"status"`
 }
 
 func (cond *TimeoutCondition) Handle(results chan<- error, stop <-chan bool, logger *log.Logger) {
@@ -12,14 +12,6 @@ type HostsEntry struct {
 	Name string `json:"name"`
 }
 
-// GenerateCommandLine generates the command line flags for this mount
-func (hosts *HostsEntry) GenerateCommandLine() []string {
-	hostsArray := make([]string, 2)
-	hostsArray[0] = "--hosts-entry"
-	hostsArray[1] = fmt.Sprintf("%s=%s", hosts.IP, hosts.Name)
-	return hostsArray
-}
-
 // HostsEntryFromString will generate a hostsEntry from a string in IP=NAME format
 func HostsEntryFromString(entry string) (HostsEntry, error) {
 	entryArray := strings.SplitN(entry, "=", 2)
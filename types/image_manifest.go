@@ -1,4 +1,4 @@
-package rkt
+package types
 
 // ImageManifest holds information about an image.  Note: we only grab what we need at this point (or what is easy)
 type ImageManifest struct {
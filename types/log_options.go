@@ -0,0 +1,13 @@
+package types
+
+import "time"
+
+// LogOptions controls which output of a pod is retrieved by a runtime
+type LogOptions struct {
+	// Follow will keep streaming new output once the existing output has been printed
+	Follow bool
+	// Since limits output to that produced after this time.  The zero time means no limit
+	Since time.Time
+	// Tail limits output to this many of the most recent lines.  Negative values mean no limit
+	Tail int
+}
@@ -0,0 +1,7 @@
+package types
+
+// Mount defines a volume that is mounted into a container
+type Mount struct {
+	Volume string `json:"volume"`
+	Path   string `json:"path"`
+}
@@ -1,10 +1,15 @@
-package rkt
+package types
 
 import (
 	"encoding/json"
 	"time"
 )
 
+// Pods stores a number of pods indexed by their appName
+type Pods struct {
+	Pods map[string]Pod
+}
+
 // Pod holds information about containers
 type Pod struct {
 	Name      string    `json:"name"`
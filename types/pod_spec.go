@@ -0,0 +1,25 @@
+package types
+
+// PodSpec holds everything a runtime needs to know in order to start a pod for a container.  It is built by the
+// container package and handed to a runtime, which turns it into whatever command line that runtime expects.
+type PodSpec struct {
+	// ProjectName is the project this pod is being run under
+	ProjectName string
+	// Name is the name of the container as defined in the config.  It is also used as the hostname of the pod
+	Name string
+	// AppName is the name the runtime will know this pod by (see Runtime.GetAppName)
+	AppName string
+	// ConfigPath is the folder used to store files for this project
+	ConfigPath string
+	Image      string
+	// Exec is the command to run in place of the default command of the image, already split into its parts
+	Exec        []string
+	Environment map[string]string
+	Mounts      []Mount
+	Volumes     map[string]Volume
+	Ports       []*Port
+	// HostsEntries are extra hosts entries that are added into every pod in the project
+	HostsEntries []HostsEntry
+	// DependencyHosts are the hosts entries for the dependencies of this container
+	DependencyHosts []HostsEntry
+}
@@ -1,25 +1,14 @@
-package container
+package types
 
 import (
-	"fmt"
 	"net"
 	"strings"
-
-	"github.com/dansteen/constellation/rkt"
 )
 
 // Port represents a port that is defined in a container manifest.  We ingest all the values even though we only use a few.
 type Port struct {
-	rkt.ImageAppPort
-	HostPort int
-}
-
-// GenerateCommandLine will generate the command line options to use this port
-func (port *Port) GenerateCommandLine() []string {
-	portArray := make([]string, 2)
-	portArray[0] = "--port"
-	portArray[1] = fmt.Sprintf("%s:%d", port.Name, port.HostPort)
-	return portArray
+	ImageAppPort
+	HostPort int `json:"host_port"`
 }
 
 // SetHostPort will get a free port on the host machine and save it as the mapped port.  You want to do this as close to the actual
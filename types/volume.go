@@ -1,7 +1,6 @@
 package types
 
 import (
-	"fmt"
 	"log"
 	"os"
 )
@@ -16,14 +15,6 @@ type Volume struct {
 	Mode os.FileMode `json:"mode"`
 }
 
-// GenerateCommandLine generates the command line flags for this mount
-func (volume *Volume) GenerateCommandLine() []string {
-	volumeArray := make([]string, 2)
-	volumeArray[0] = "--volume"
-	volumeArray[1] = fmt.Sprintf("%s,kind=%s,source=%s", volume.Name, volume.Kind, volume.Path)
-	return volumeArray
-}
-
 // CreateDir creates the directory pointed to in this volume if it does not exist
 // this is only done if the volume.Kind is set to "host".
 func (volume *Volume) CreateDir() error {
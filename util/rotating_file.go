@@ -0,0 +1,109 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+)
+
+// RotatingFile is a file that is rotated once it grows past MaxSize.  Rotated files are renamed to <path>.1, <path>.2 etc.
+// and at most MaxFiles of them are kept.
+type RotatingFile struct {
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotatingFile will open (or create) a rotating file at filePath.  Existing content is appended to.
+func OpenRotatingFile(filePath string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	rotating := &RotatingFile{
+		Path:     filePath,
+		MaxSize:  maxSize,
+		MaxFiles: maxFiles,
+	}
+	err := os.MkdirAll(path.Dir(filePath), 0755)
+	if err != nil {
+		return nil, err
+	}
+	err = rotating.open()
+	if err != nil {
+		return nil, err
+	}
+	return rotating, nil
+}
+
+// Write will write to the file, rotating it first if the write would take it past MaxSize
+func (rotating *RotatingFile) Write(data []byte) (int, error) {
+	rotating.lock.Lock()
+	defer rotating.lock.Unlock()
+	if rotating.size > 0 && rotating.size+int64(len(data)) > rotating.MaxSize {
+		err := rotating.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	written, err := rotating.file.Write(data)
+	rotating.size += int64(written)
+	return written, err
+}
+
+// Close will close the file
+func (rotating *RotatingFile) Close() error {
+	rotating.lock.Lock()
+	defer rotating.lock.Unlock()
+	return rotating.file.Close()
+}
+
+// RotatedPaths returns the paths of the rotated files for filePath that exist, oldest first
+func RotatedPaths(filePath string, maxFiles int) []string {
+	paths := make([]string, 0)
+	for index := maxFiles; index > 0; index-- {
+		rotatedPath := fmt.Sprintf("%s.%d", filePath, index)
+		if _, err := os.Stat(rotatedPath); err == nil {
+			paths = append(paths, rotatedPath)
+		}
+	}
+	return paths
+}
+
+// open will open the file at Path for appending
+func (rotating *RotatingFile) open() error {
+	file, err := os.OpenFile(rotating.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rotating.file = file
+	rotating.size = info.Size()
+	return nil
+}
+
+// rotate will shift each of the rotated files along by one, dropping the oldest, and then start a new file
+func (rotating *RotatingFile) rotate() error {
+	err := rotating.file.Close()
+	if err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", rotating.Path, rotating.MaxFiles))
+	for index := rotating.MaxFiles - 1; index > 0; index-- {
+		os.Rename(fmt.Sprintf("%s.%d", rotating.Path, index), fmt.Sprintf("%s.%d", rotating.Path, index+1))
+	}
+	if rotating.MaxFiles > 0 {
+		err = os.Rename(rotating.Path, fmt.Sprintf("%s.1", rotating.Path))
+	} else {
+		err = os.Remove(rotating.Path)
+	}
+	if err != nil {
+		return err
+	}
+	return rotating.open()
+}